@@ -0,0 +1,211 @@
+// Package lexer splits ORB schema text into tokens.
+//
+// The lexer knows nothing about the meaning of a line. It only classifies
+// characters, so the same token stream can be used by the parser, formatters
+// and editor tooling. Whitespace other than newlines is skipped, but every
+// token records its position, so callers can recover the exact source text
+// between any two tokens.
+//
+// A # that starts a line is a Hash token, for directives and comment lines.
+// Any later # starts a trailing Comment that runs to the end of the line,
+// unless it is escaped as \#, which is lexed as an Other token.
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Lexer struct {
+	src  string
+	off  int
+	base Pos
+	line int
+	col  int
+	// first is set until the first token of each line has been read
+	first bool
+}
+
+func New(src string) *Lexer {
+	return NewAt(src, Pos{Line: 1, Column: 1})
+}
+
+// NewAt returns a lexer for src, which starts at base in some larger file.
+// It lets a caller lex one line at a time and still get file positions.
+func NewAt(src string, base Pos) *Lexer {
+	return &Lexer{src: src, base: base, line: base.Line, col: base.Column, first: true}
+}
+
+// Lex returns every token in src. The last token is always EOF.
+func Lex(src string) []Token {
+	return lexAll(New(src))
+}
+
+// LexAt is Lex for text that starts at base.
+func LexAt(src string, base Pos) []Token {
+	return lexAll(NewAt(src, base))
+}
+
+func lexAll(l *Lexer) []Token {
+	var toks []Token
+	for {
+		tok := l.Next()
+		toks = append(toks, tok)
+		if tok.Kind == EOF {
+			return toks
+		}
+	}
+}
+
+func (l *Lexer) pos() Pos {
+	return Pos{File: l.base.File, Offset: l.base.Offset + l.off, Line: l.line, Column: l.col}
+}
+
+func (l *Lexer) token(kind Kind, start Pos) Token {
+	return Token{Kind: kind, Text: l.src[start.Offset-l.base.Offset : l.off], Pos: start}
+}
+
+func (l *Lexer) advance(n int) {
+	l.off += n
+	l.col += n
+}
+
+func (l *Lexer) skipSpace() {
+	for l.off < len(l.src) {
+		switch l.src[l.off] {
+		case ' ', '\t', '\r', '\f', '\v':
+			l.advance(1)
+		default:
+			return
+		}
+	}
+}
+
+// Next returns the next token. Once the input is exhausted it keeps returning EOF.
+func (l *Lexer) Next() Token {
+	l.skipSpace()
+	start := l.pos()
+	if l.off >= len(l.src) {
+		return Token{Kind: EOF, Pos: start}
+	}
+
+	c := l.src[l.off]
+	first := l.first
+	l.first = false
+	switch c {
+	case '\n':
+		l.advance(1)
+		tok := l.token(Newline, start)
+		l.line += 1
+		l.col = 1
+		l.first = true
+		return tok
+	case '[':
+		l.advance(1)
+		return l.token(LBracket, start)
+	case ']':
+		l.advance(1)
+		return l.token(RBracket, start)
+	case '#':
+		if !first {
+			return l.lexComment(start)
+		}
+		l.advance(1)
+		return l.token(Hash, start)
+	case '\\':
+		if l.off+1 < len(l.src) && l.src[l.off+1] == '#' {
+			l.advance(2)
+			return l.token(Other, start)
+		}
+	case '=':
+		l.advance(1)
+		return l.token(Equals, start)
+	case '-':
+		l.advance(1)
+		return l.token(Dash, start)
+	case ':':
+		l.advance(1)
+		return l.token(Colon, start)
+	case ',':
+		l.advance(1)
+		return l.token(Comma, start)
+	case '.':
+		l.advance(1)
+		return l.token(Dot, start)
+	case '@':
+		l.advance(1)
+		return l.token(At, start)
+	case '|':
+		l.advance(1)
+		return l.token(Pipe, start)
+	case '!':
+		l.advance(1)
+		return l.token(Bang, start)
+	case '(':
+		l.advance(1)
+		return l.token(LParen, start)
+	case ')':
+		l.advance(1)
+		return l.token(RParen, start)
+	case '\'', '"':
+		return l.lexString(start, c)
+	}
+
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	if isIdent(r) {
+		return l.lexIdent(start)
+	}
+	l.advance(size)
+	return l.token(Other, start)
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (l *Lexer) lexIdent(start Pos) Token {
+	for l.off < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.off:])
+		if !isIdent(r) {
+			break
+		}
+		l.advance(size)
+	}
+	tok := l.token(Ident, start)
+	if kind, ok := keywords[tok.Text]; ok {
+		tok.Kind = kind
+	}
+	return tok
+}
+
+func (l *Lexer) lexComment(start Pos) Token {
+	for l.off < len(l.src) && l.src[l.off] != '\n' {
+		l.advance(1)
+	}
+	tok := l.token(Comment, start)
+	tok.Text = strings.TrimRight(tok.Text, " \t\r")
+	return tok
+}
+
+// A string literal runs to the next matching quote on the same line, skipping
+// over backslash escapes. If the line ends first, the partial literal is
+// returned as Illegal. The escapes themselves are checked by Unquote.
+func (l *Lexer) lexString(start Pos, quote byte) Token {
+	l.advance(1)
+	for l.off < len(l.src) {
+		switch l.src[l.off] {
+		case quote:
+			l.advance(1)
+			return l.token(String, start)
+		case '\n':
+			return l.token(Illegal, start)
+		case '\\':
+			if l.off+1 < len(l.src) && l.src[l.off+1] != '\n' {
+				l.advance(1)
+			}
+		}
+		l.advance(1)
+	}
+	return l.token(Illegal, start)
+}
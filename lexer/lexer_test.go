@@ -0,0 +1,110 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func kinds(toks []Token) []Kind {
+	var out []Kind
+	for _, tok := range toks {
+		out = append(out, tok.Kind)
+	}
+	return out
+}
+
+func sameKinds(a, b []Kind) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var lexKindsTests = []struct {
+	name     string
+	input    string
+	expected []Kind
+}{
+	{"Empty Input", "", []Kind{EOF}},
+	{"Whitespace Only", " \t ", []Kind{EOF}},
+	{"Directive", "#language = go", []Kind{Hash, Ident, Equals, Ident, EOF}},
+	{"Table Header", "[student]", []Kind{LBracket, Ident, RBracket, EOF}},
+	{"Column", "sid int using sequence", []Kind{Ident, Ident, Using, Ident, EOF}},
+	{"Constraint", "- primary key", []Kind{Dash, Ident, Ident, EOF}},
+	{"Constraint With Value", "-default: 'John Doe'", []Kind{Dash, Ident, Colon, String, EOF}},
+	{"Quoted Keyword", "-default: 'alias'", []Kind{Dash, Ident, Colon, String, EOF}},
+	{"Double Quotes", `"a b"`, []Kind{String, EOF}},
+	{"Escaped Quote", `"say \"hi\"" x`, []Kind{String, Ident, EOF}},
+	{"Escaped Backslash Before Quote", `'a\\' x`, []Kind{String, Ident, EOF}},
+	{"Unterminated String", "-default: 'John", []Kind{Dash, Ident, Colon, Illegal, EOF}},
+	{"Other Characters", "price < 100;", []Kind{Ident, Other, Ident, Other, EOF}},
+	{"Parentheses", "VARCHAR(32)", []Kind{Ident, LParen, Ident, RParen, EOF}},
+	{"Table Line", "!unique(a, b)", []Kind{Bang, Ident, LParen, Ident, Comma, Ident, RParen, EOF}},
+	{"Newlines", "[a]\nid int\n", []Kind{LBracket, Ident, RBracket, Newline, Ident, Ident, Newline, EOF}},
+	{"Trailing Comment", "name string using TEXT # display name", []Kind{Ident, Ident, Using, Ident, Comment, EOF}},
+	{"Comment After Hash", "## heading", []Kind{Hash, Comment, EOF}},
+	{"Comment On Each Line", "# a # b\nid int # c", []Kind{Hash, Ident, Comment, Newline, Ident, Ident, Comment, EOF}},
+	{"Escaped Hash", `-check: a \# b`, []Kind{Dash, Ident, Colon, Ident, Other, Ident, EOF}},
+	{"Hash In String", "-default: '#1' # first", []Kind{Dash, Ident, Colon, String, Comment, EOF}},
+	{"Continuation", "  | AND price < 100", []Kind{Pipe, Ident, Ident, Other, Ident, EOF}},
+	{"Directive List", "#language = go, typescript", []Kind{Hash, Ident, Equals, Ident, Comma, Ident, EOF}},
+	{"Versioned Directive", "#go.package = postgres@15", []Kind{Hash, Ident, Dot, Ident, Equals, Ident, At, Ident, EOF}},
+	{"Unicode Identifier", "préféré int", []Kind{Ident, Ident, EOF}},
+}
+
+func TestLexKinds(t *testing.T) {
+	for _, test := range lexKindsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			got := kinds(Lex(test.input))
+			if !sameKinds(got, test.expected) {
+				tt.Fatalf("Incorrect tokens for %q. Expected %v; got %v", test.input, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	toks := Lex("[a]\n  id int")
+	expected := []Pos{
+		{Offset: 0, Line: 1, Column: 1},
+		{Offset: 1, Line: 1, Column: 2},
+		{Offset: 2, Line: 1, Column: 3},
+		{Offset: 3, Line: 1, Column: 4},
+		{Offset: 6, Line: 2, Column: 3},
+		{Offset: 9, Line: 2, Column: 6},
+		{Offset: 12, Line: 2, Column: 9},
+	}
+	if len(toks) != len(expected) {
+		t.Fatalf("Expected %d tokens; got %v", len(expected), toks)
+	}
+	for i, tok := range toks {
+		if tok.Pos != expected[i] {
+			t.Errorf("Token %v at %+v; expected %+v", tok, tok.Pos, expected[i])
+		}
+	}
+}
+
+func TestLexText(t *testing.T) {
+	src := "name string using double precision"
+	for _, tok := range Lex(src) {
+		if src[tok.Pos.Offset:tok.End().Offset] != tok.Text {
+			t.Fatalf("Token %v does not match its source span", tok)
+		}
+	}
+}
+
+func TestLexAt(t *testing.T) {
+	base := Pos{File: "a.orb", Offset: 40, Line: 7, Column: 1}
+	toks := LexAt("  -unique", base)
+	expected := Pos{File: "a.orb", Offset: 43, Line: 7, Column: 4}
+	if toks[1].Pos != expected {
+		t.Fatalf("Incorrect position. Expected %+v; got %+v", expected, toks[1].Pos)
+	}
+	if end := toks[1].End(); end.Offset != 49 || end.Column != 10 {
+		t.Fatalf("Incorrect end position %+v", end)
+	}
+}
@@ -0,0 +1,96 @@
+package lexer
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrSyntax = errors.New("invalid string literal")
+
+// Unquote returns the value of a String token's text, with its quotes
+// removed and escapes replaced. Either quote character may be used, and the
+// escapes are \\, \', \", \#, \n, \r, \t, \uXXXX and \UXXXXXXXX.
+func Unquote(lit string) (string, error) {
+	if len(lit) < 2 || (lit[0] != '\'' && lit[0] != '"') || lit[len(lit)-1] != lit[0] {
+		return "", ErrSyntax
+	}
+	quote := lit[0]
+	body := lit[1 : len(lit)-1]
+	if !strings.Contains(body, `\`) {
+		if strings.IndexByte(body, quote) >= 0 {
+			return "", ErrSyntax
+		}
+		return body, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == quote {
+			return "", ErrSyntax
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			continue
+		}
+		i += 1
+		if i >= len(body) {
+			return "", ErrSyntax
+		}
+		switch body[i] {
+		case '\\', '\'', '"', '#':
+			out.WriteByte(body[i])
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'u', 'U':
+			size := 4
+			if body[i] == 'U' {
+				size = 8
+			}
+			if i+1+size > len(body) {
+				return "", ErrSyntax
+			}
+			code, err := strconv.ParseUint(body[i+1:i+1+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", ErrSyntax
+			}
+			out.WriteRune(rune(code))
+			i += size
+		default:
+			return "", ErrSyntax
+		}
+	}
+	return out.String(), nil
+}
+
+// Quote returns a string literal, using the given quote character, that
+// Unquote turns back into s.
+func Quote(s string, quote byte) string {
+	var out strings.Builder
+	out.WriteByte(quote)
+	for _, r := range s {
+		switch r {
+		case '\\':
+			out.WriteString(`\\`)
+		case rune(quote):
+			out.WriteByte('\\')
+			out.WriteByte(quote)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte(quote)
+	return out.String()
+}
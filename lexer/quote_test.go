@@ -0,0 +1,64 @@
+package lexer
+
+import (
+	"testing"
+)
+
+var unquoteMatchesTests = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{"Single Quotes", `'John Doe'`, "John Doe"},
+	{"Double Quotes", `"John Doe"`, "John Doe"},
+	{"Empty", `''`, ""},
+	{"Other Quote Inside", `"it's"`, "it's"},
+	{"Escaped Quote", `"say \"hi\""`, `say "hi"`},
+	{"Escaped Single Quote", `'it\'s'`, "it's"},
+	{"Backslash", `'C:\\orb'`, `C:\orb`},
+	{"Newline And Tab", `"a\nb\tc"`, "a\nb\tc"},
+	{"Hash", `'\#1'`, "#1"},
+	{"Unicode Escape", `"caf\u00e9"`, "café"},
+	{"Long Unicode Escape", `"\U0001F600"`, "\U0001F600"},
+	{"Colon And Spaces", `"postgres: 15 beta"`, "postgres: 15 beta"},
+}
+
+func TestUnquoteMatches(t *testing.T) {
+	for _, test := range unquoteMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			got, err := Unquote(test.input)
+			if err != nil {
+				tt.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.expected {
+				tt.Fatalf("Incorrect value. Expected %q; got %q", test.expected, got)
+			}
+			if again, err := Unquote(Quote(got, test.input[0])); err != nil || again != got {
+				tt.Fatalf("Quote does not round trip: %q", Quote(got, test.input[0]))
+			}
+		})
+	}
+}
+
+var unquoteRejectsTests = []struct {
+	name  string
+	input string
+}{
+	{"Unquoted", "abc"},
+	{"Mismatched Quotes", `'abc"`},
+	{"Unknown Escape", `'\d+'`},
+	{"Trailing Backslash", `'abc\'`},
+	{"Short Unicode Escape", `'\u00e'`},
+	{"Bad Unicode Escape", `'\uzzzz'`},
+	{"Unescaped Quote", `'a'b'`},
+}
+
+func TestUnquoteRejects(t *testing.T) {
+	for _, test := range unquoteRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			if got, err := Unquote(test.input); err == nil {
+				tt.Fatalf("Expected error for %s; got %q", test.input, got)
+			}
+		})
+	}
+}
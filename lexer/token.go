@@ -0,0 +1,139 @@
+package lexer
+
+import (
+	"strconv"
+)
+
+// Kind identifies the lexical class of a Token.
+type Kind int
+
+const (
+	EOF Kind = iota
+	// Illegal marks text that could not be lexed, such as an unterminated string literal.
+	Illegal
+	Newline
+	Ident
+	String
+	Using
+	LBracket
+	RBracket
+	Hash
+	Equals
+	Dash
+	Colon
+	Comma
+	Dot
+	At
+	// Pipe starts a continuation line.
+	Pipe
+	// Bang starts a table-level line, such as a composite key.
+	Bang
+	LParen
+	RParen
+	// Comment is a trailing comment, from a # that isn't the first token on
+	// its line to the end of the line.
+	Comment
+	// Other is any single character that has no token of its own. The parser
+	// treats runs of these as raw text, e.g. the SQL in a requested type.
+	Other
+)
+
+var kindNames = [...]string{
+	EOF:      "EOF",
+	Illegal:  "Illegal",
+	Newline:  "Newline",
+	Ident:    "Ident",
+	String:   "String",
+	Using:    "using",
+	LBracket: "[",
+	RBracket: "]",
+	Hash:     "#",
+	Equals:   "=",
+	Dash:     "-",
+	Colon:    ":",
+	Comma:    ",",
+	Dot:      ".",
+	At:       "@",
+	Pipe:     "|",
+	Bang:     "!",
+	LParen:   "(",
+	RParen:   ")",
+	Comment:  "Comment",
+	Other:    "Other",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "Kind(?)"
+	}
+	return kindNames[k]
+}
+
+var keywords = map[string]Kind{
+	"using": Using,
+}
+
+// Pos is a location in the source text. Line and Column are 1-based, and
+// Column counts bytes. Offset is the 0-based byte offset into the file.
+type Pos struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position has been set.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as file:line:column, leaving out the file
+// name when there is none.
+func (p Pos) String() string {
+	out := ""
+	if p.File != "" {
+		out += p.File + ":"
+	}
+	if !p.IsValid() {
+		return out + "-"
+	}
+	return out + strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// Span is the half-open source range [Start, End).
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+type Token struct {
+	Kind Kind
+	// Text is the token exactly as it appears in the source, quotes included.
+	Text string
+	Pos  Pos
+}
+
+// End returns the position just past the token. Tokens never span lines, so
+// it is on the same line as the token itself.
+func (t Token) End() Pos {
+	end := t.Pos
+	end.Offset += len(t.Text)
+	end.Column += len(t.Text)
+	return end
+}
+
+func (t Token) Span() Span {
+	return Span{t.Pos, t.End()}
+}
+
+func (t Token) String() string {
+	switch t.Kind {
+	case EOF, Newline:
+		return t.Kind.String()
+	}
+	return t.Kind.String() + "(" + t.Text + ")"
+}
@@ -9,9 +9,18 @@ import (
 
 
 func main() {
-	tree, err := parser.Parse(os.Stdin)
+	var tree *parser.ParseTree
+	var err []error
+	if len(os.Args) > 1 {
+		tree, err = parser.ParseFile(os.Args[1])
+	} else {
+		tree, err = parser.Parse(os.Stdin)
+	}
 	if err != nil {
 		fmt.Println(err)
 	}
+	for _, w := range tree.Warnings {
+		fmt.Println("warning:", w)
+	}
 	fmt.Println(tree)
 }
@@ -0,0 +1,344 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// DialectType is a requested type that only applies to some dialects. It
+// comes from a `using` clause with `when` qualifiers:
+//
+//	data json using JSONB when postgres, TEXT when sqlite
+//
+// Here JSONB applies to Postgres and TEXT to SQLite. An alternative with no
+// qualifier is the type for every other dialect, and is kept in
+// Column.RequestedType.
+type DialectType struct {
+	Type string
+	Dialects []Dialect
+}
+
+func (t *DialectType) String() string {
+	return t.Type + whenString(t.Dialects)
+}
+
+// whenString formats a `when` qualifier, for String methods.
+func whenString(dialects []Dialect) string {
+	if dialects == nil {
+		return ""
+	}
+	var names []string
+	for _, d := range dialects {
+		names = append(names, d.String())
+	}
+	return " when " + strings.Join(names, " or ")
+}
+
+// appliesTo reports whether a line qualified by dialects applies to d. An
+// unqualified line applies to every dialect.
+func appliesTo(dialects []Dialect, d Dialect) bool {
+	if dialects == nil {
+		return true
+	}
+	for _, e := range dialects {
+		if e == d {
+			return true
+		}
+	}
+	return false
+}
+
+// requestedTypeString formats the types after `using`, or returns "" if
+// there are none.
+func requestedTypeString(requested string, types []*DialectType) string {
+	var alts []string
+	for _, t := range types {
+		alts = append(alts, t.String())
+	}
+	if requested != "" {
+		alts = append(alts, requested)
+	}
+	return strings.Join(alts, ", ")
+}
+
+// parseRequestedType reads the rest of c as the types after `using`. If no
+// alternative has a `when` qualifier, the whole text is the one requested
+// type, commas and all, as a type like DECIMAL(10, 2) needs them. Otherwise
+// the alternatives are split at the commas outside parentheses, and at most
+// one of them may be unqualified.
+func parseRequestedType(c *cursor) (string, []*DialectType, error) {
+	var alts [][2]int
+	depth, start := 0, c.i
+	for i := c.i; ; i++ {
+		switch c.toks[i].Kind {
+		case lexer.LParen:
+			depth++
+		case lexer.RParen:
+			depth--
+		case lexer.Comma, lexer.EOF:
+			if depth > 0 && c.toks[i].Kind == lexer.Comma {
+				continue
+			}
+			alts = append(alts, [2]int{start, i})
+			start = i + 1
+		}
+		if c.toks[i].Kind == lexer.EOF {
+			break
+		}
+	}
+	qualified := false
+	for _, alt := range alts {
+		if c.whenAt(alt[0], alt[1]) >= 0 {
+			qualified = true
+		}
+	}
+	if !qualified {
+		requested := c.rest()
+		if requested == "" {
+			return "", nil, errorAt("Missing type after using", c.peek().Pos)
+		}
+		return requested, nil, nil
+	}
+
+	var requested string
+	var types []*DialectType
+	seen := map[Dialect]bool{}
+	for _, alt := range alts {
+		end := alt[1]
+		when := c.whenAt(alt[0], end)
+		if when >= 0 {
+			end = when
+		}
+		typ := strings.TrimSpace(c.text(alt[0], end))
+		if typ == "" {
+			return "", nil, errorAt("Missing type after using", c.toks[alt[0]].Pos)
+		}
+		if when < 0 {
+			if requested != "" {
+				return "", nil, errorAt("Only one type can apply to every dialect", c.toks[alt[0]].Pos)
+			}
+			requested = typ
+			continue
+		}
+		dialects, err := c.parseDialects(when+1, alt[1])
+		if err != nil {
+			return "", nil, err
+		}
+		for _, d := range dialects {
+			if seen[d] {
+				return "", nil, errorAt("Dialect "+d.String()+" already has a type", c.toks[when].Pos)
+			}
+			seen[d] = true
+		}
+		types = append(types, &DialectType{Type: typ, Dialects: dialects})
+	}
+	c.i = len(c.toks) - 1
+	return requested, types, nil
+}
+
+// whenAt returns the index of the `when` that qualifies tokens [from, to),
+// or -1 if they have no qualifier.
+func (c *cursor) whenAt(from, to int) int {
+	for i := to - 1; i >= from; i-- {
+		if tok := c.toks[i]; tok.Kind == lexer.Ident && tok.Text == "when" {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseDialects reads the dialect names in tokens [from, to), separated by
+// `or`.
+func (c *cursor) parseDialects(from, to int) ([]Dialect, error) {
+	var dialects []Dialect
+	for i := from; ; i += 2 {
+		if i >= to || c.toks[i].Kind != lexer.Ident {
+			return nil, errorAt("Missing dialect after when", c.toks[i].Pos)
+		}
+		d, ok := ParseDialect(c.toks[i].Text)
+		if !ok {
+			return nil, errorAt("Unknown dialect "+c.toks[i].Text, c.toks[i].Pos)
+		}
+		dialects = append(dialects, d)
+		if i+1 == to {
+			return dialects, nil
+		}
+		if tok := c.toks[i+1]; tok.Kind != lexer.Ident || tok.Text != "or" {
+			return nil, errorAt("Expected or between dialects", tok.Pos)
+		}
+	}
+}
+
+// trailingWhen takes a bracketed `when` qualifier off the end of a
+// constraint line and returns its dialects, or nil if the line doesn't end
+// with one:
+//
+//	-default: now() [when postgres or mysql]
+//
+// Constraint values are free text, so the brackets keep a value such as
+// `used when sqlite` from being read as a qualifier.
+func (c *cursor) trailingWhen() ([]Dialect, error) {
+	end := len(c.toks) - 1
+	if end <= c.i || c.toks[end-1].Kind != lexer.RBracket {
+		return nil, nil
+	}
+	open := end - 2
+	for open >= c.i && c.toks[open].Kind != lexer.LBracket {
+		open--
+	}
+	if open < c.i || c.toks[open+1].Kind != lexer.Ident || c.toks[open+1].Text != "when" {
+		return nil, nil
+	}
+	dialects, err := c.parseDialects(open+2, end-1)
+	if err != nil {
+		return nil, err
+	}
+	c.toks = append(c.toks[:open], lexer.Token{Kind: lexer.EOF, Pos: c.toks[open].Pos})
+	return dialects, nil
+}
+
+// Project returns the tree as it applies to dialect d. Types and constraints
+// qualified with `when` are kept only if they apply to d, and lose their
+// qualifiers, and the `#database` directive is set to d. The tree itself is
+// left as it is.
+func (p *ParseTree) Project(d Dialect) *ParseTree {
+	q := *p
+	q.Directives = append([]*Directive{}, p.Directives...)
+	db := &Directive{Name: "database", Items: []*DirectiveItem{{Value: d.String()}}}
+	if old := p.Directive("database"); old != nil {
+		db.Span = old.Span
+		for i := len(q.Directives) - 1; i >= 0; i-- {
+			if q.Directives[i] == old {
+				q.Directives[i] = db
+				break
+			}
+		}
+	} else {
+		q.Directives = append(q.Directives, db)
+	}
+	types := map[*NamedType]*NamedType{}
+	q.Types = nil
+	for _, n := range p.Types {
+		types[n] = n.project(d)
+		q.Types = append(q.Types, types[n])
+	}
+	for _, n := range q.Types {
+		if n.base != nil {
+			n.base = types[n.base]
+		}
+	}
+	q.Tables = projectTables(p.Tables, d)
+	q.Mixins = projectTables(p.Mixins, d)
+	q.Views = nil
+	for _, v := range p.Views {
+		w := *v
+		w.Columns = projectColumns(v.Columns, d)
+		q.Views = append(q.Views, &w)
+	}
+	return &q
+}
+
+func projectTables(tables []*Table, d Dialect) []*Table {
+	var out []*Table
+	for _, t := range tables {
+		u := *t
+		u.Columns = projectColumns(t.Columns, d)
+		out = append(out, &u)
+	}
+	return out
+}
+
+func projectColumns(columns []*Column, d Dialect) []*Column {
+	var out []*Column
+	for _, c := range columns {
+		col := *c
+		col.RequestedType = projectType(c.RequestedType, c.DialectTypes, d)
+		col.DialectTypes = nil
+		col.Nullable = projectNullable(c.Nullable, c.Constraints, d)
+		col.Constraints = projectConstraints(c.Constraints, d)
+		col.referenceAt = 0
+		own := 0
+		for _, con := range c.Constraints {
+			if con.From != "" {
+				continue
+			}
+			if own == c.referenceAt {
+				break
+			}
+			if appliesTo(con.Dialects, d) {
+				col.referenceAt++
+			}
+			own++
+		}
+		if c.named != nil {
+			col.named = c.named.project(d)
+		}
+		out = append(out, &col)
+	}
+	return out
+}
+
+func (n *NamedType) project(d Dialect) *NamedType {
+	m := *n
+	m.RequestedType = projectType(n.RequestedType, n.DialectTypes, d)
+	m.DialectTypes = nil
+	m.Nullable = projectNullable(n.Nullable, n.Constraints, d)
+	m.Constraints = projectConstraints(n.Constraints, d)
+	return &m
+}
+
+// projectNullable returns whether a column or named type is nullable in d.
+// A NULL, NOT NULL or PRIMARY KEY constraint qualified for d overrides the
+// optional mark and any unqualified constraint.
+func projectNullable(nullable bool, constraints []*Constraint, d Dialect) bool {
+	var qualified []*Constraint
+	for _, con := range constraints {
+		if con.Dialects != nil && appliesTo(con.Dialects, d) {
+			qualified = append(qualified, con)
+		}
+	}
+	for _, con := range qualified {
+		if nullKind(con) == "NOT NULL" {
+			return false
+		}
+	}
+	for _, con := range qualified {
+		if nullKind(con) == "NULL" {
+			return true
+		}
+	}
+	return nullable
+}
+
+// projectType returns the requested type that applies to d.
+func projectType(requested string, types []*DialectType, d Dialect) string {
+	for _, t := range types {
+		if appliesTo(t.Dialects, d) {
+			return t.Type
+		}
+	}
+	return requested
+}
+
+// projectConstraints returns the constraints that apply to d. A constraint
+// taken from a named type is left out if the column has its own of the same
+// name for d.
+func projectConstraints(constraints []*Constraint, d Dialect) []*Constraint {
+	var own []*Constraint
+	for _, con := range constraints {
+		if con.From == "" && appliesTo(con.Dialects, d) {
+			own = append(own, con)
+		}
+	}
+	var out []*Constraint
+	for _, con := range constraints {
+		if !appliesTo(con.Dialects, d) || con.From != "" && hasConstraintNamed(own, con.Name) {
+			continue
+		}
+		c := *con
+		c.Dialects = nil
+		out = append(out, &c)
+	}
+	return out
+}
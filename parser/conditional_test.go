@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var conditionalMatchesTests = []struct {
+	name string
+	input string
+	expected Column
+}{
+	{"Plain Using", "data json using DECIMAL(10, 2)", Column{Name: "data", Type: "json", RequestedType: "DECIMAL(10, 2)"}},
+	{"Dialect Types", "data json using JSONB when postgres, TEXT when sqlite", Column{Name: "data", Type: "json", DialectTypes: []*DialectType{
+		{Type: "JSONB", Dialects: []Dialect{Postgres}},
+		{Type: "TEXT", Dialects: []Dialect{SQLite}},
+	}}},
+	{"Default Type", "price decimal using NUMERIC(10, 2) when postgres or mysql, TEXT", Column{Name: "price", Type: "decimal", RequestedType: "TEXT", DialectTypes: []*DialectType{
+		{Type: "NUMERIC(10, 2)", Dialects: []Dialect{Postgres, MySQL}},
+	}}},
+	{"Constraint", "created time\n-default: now() [when postgres]\n-default: CURRENT_TIMESTAMP [when sqlite3]", Column{Name: "created", Type: "time", Constraints: []*Constraint{
+		{Name: "default", Value: "now()", Dialects: []Dialect{Postgres}},
+		{Name: "default", Value: "CURRENT_TIMESTAMP", Dialects: []Dialect{SQLite}},
+	}}},
+	{"Constraint Without Value", "name string\n-unique [when mysql or sqlite] # case-insensitive", Column{Name: "name", Type: "string", Constraints: []*Constraint{
+		{Name: "unique", Dialects: []Dialect{MySQL, SQLite}, Comment: "case-insensitive"},
+	}}},
+	{"Quoted Value", "name string\n-default: '[when sqlite]' [when sqlite]", Column{Name: "name", Type: "string", Constraints: []*Constraint{
+		{Name: "default", Value: "[when sqlite]", Quoted: true, Dialects: []Dialect{SQLite}},
+	}}},
+	{"Continued Value", "price int\n-check: price > 0\n  | AND price < 100 [when postgres]", Column{Name: "price", Type: "int", Constraints: []*Constraint{
+		{Name: "check", Value: "price > 0 AND price < 100", Lines: []string{"price > 0", "AND price < 100"}, Dialects: []Dialect{Postgres}},
+	}}},
+	{"Unbracketed When", "state string\n-comment: used when sqlite", Column{Name: "state", Type: "string", Constraints: []*Constraint{
+		{Name: "comment", Value: "used when sqlite"},
+	}}},
+	{"Other Brackets", "tags string\n-default: ARRAY[1]", Column{Name: "tags", Type: "string", Constraints: []*Constraint{
+		{Name: "default", Value: "ARRAY[1]"},
+	}}},
+}
+
+func TestParseConditionalMatches(t *testing.T) {
+	for _, test := range conditionalMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !col.Equals(test.expected) {
+				tt.Fatalf("Incorrect column. Expected:\n%s\ngot:\n%s", &test.expected, col)
+			}
+			again, errs := ParseColumn(DummyScanner(col.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Column not preserved by String:\n%s", col)
+			}
+		})
+	}
+}
+
+var conditionalRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Unknown Dialect", "data json using JSONB when oracle", "1:28:Unknown dialect oracle"},
+	{"Missing Dialect", "data json using JSONB when", "1:27:Missing dialect after when"},
+	{"Missing Or", "data json using JSONB when postgres mysql", "1:37:Expected or between dialects"},
+	{"Missing Type", "data json using when postgres, TEXT", "1:17:Missing type after using"},
+	{"Two Defaults", "data json using JSONB when postgres, TEXT, BLOB", "1:44:Only one type can apply to every dialect"},
+	{"Repeated Dialect", "data json using JSONB when postgres, JSON when pg", "1:43:Dialect postgres already has a type"},
+	{"Qualifier Mid Constraint", "price int\n-check: price > 0 [when sqlite]\n  | AND price < 100", "3:1:A when qualifier must end the constraint"},
+	{"Unknown Dialect In Constraint", "price int\n-check: price > 0 [when oracle]", "2:25:Unknown dialect oracle"},
+}
+
+func TestParseConditionalRejects(t *testing.T) {
+	for _, test := range conditionalRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := ParseColumn(DummyScanner(test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+const projectInput = `#database = postgres
+#type doc = string using JSONB when postgres, TEXT - default: '{}' [when postgres]
+
+[post]
+body doc
+-default: '' [when sqlite]
+created time using TIMESTAMPTZ when postgres
+-default: now() [when postgres]
+-default: CURRENT_TIMESTAMP [when sqlite]
+-comment: set when sqlite
+`
+
+var projectTests = []struct {
+	dialect Dialect
+	expected string
+}{
+	{Postgres, "#database=postgres\n#type doc = string using JSONB - default: '{}'\n\n[post]\nbody doc\ncreated time using TIMESTAMPTZ\n-default: now()\n-comment: set when sqlite\n"},
+	{SQLite, "#database=sqlite\n#type doc = string using TEXT\n\n[post]\nbody doc\n-default: ''\ncreated time\n-default: CURRENT_TIMESTAMP\n-comment: set when sqlite\n"},
+}
+
+func TestProject(t *testing.T) {
+	tree, errs := Parse(strings.NewReader(projectInput))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	before := tree.String()
+	for _, test := range projectTests {
+		t.Run(test.dialect.String(), func(tt *testing.T) {
+			projected := tree.Project(test.dialect)
+			if got := projected.String(); got != test.expected {
+				tt.Fatalf("Incorrect projection. Expected:\n%s\ngot:\n%s", test.expected, got)
+			}
+			if d, ok := projected.Dialect(); !ok || d != test.dialect {
+				tt.Fatalf("Projected tree has dialect %v", d)
+			}
+			if got := tree.Table("post").Column("body").SQLType(test.dialect); got != projected.Table("post").Column("body").SQLType(test.dialect) {
+				tt.Fatalf("SQLType differs after projection: %s", got)
+			}
+		})
+	}
+	if tree.String() != before {
+		t.Fatalf("Project changed the tree:\n%s", tree)
+	}
+}
+
+var projectNullableTests = []struct {
+	dialect Dialect
+	expected string
+}{
+	{Postgres, "[t]\nx int\nz int\n-NOT NULL\n"},
+	{SQLite, "[t]\nx int\n-NULL\nz int?\n"},
+	{MySQL, "[t]\nx int\nz int?\n"},
+}
+
+func TestProjectNullable(t *testing.T) {
+	tree, errs := Parse(strings.NewReader("[t]\nx int\n-NULL [when sqlite]\nz int?\n-NOT NULL [when postgres]"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if x := tree.Table("t").Column("x"); x.Nullable {
+		t.Fatalf("Column only nullable on SQLite is nullable everywhere: %s", x)
+	}
+	for _, test := range projectNullableTests {
+		t.Run(test.dialect.String(), func(tt *testing.T) {
+			projected := tree.Project(test.dialect)
+			if got := projected.Tables[0].String(); got != test.expected {
+				tt.Fatalf("Incorrect projection. Expected:\n%s\ngot:\n%s", test.expected, got)
+			}
+			x, z := projected.Table("t").Column("x"), projected.Table("t").Column("z")
+			if x.Nullable != (test.dialect == SQLite) || z.Nullable != (test.dialect != Postgres) {
+				tt.Fatalf("Incorrect nullability: x %v, z %v", x.Nullable, z.Nullable)
+			}
+		})
+	}
+}
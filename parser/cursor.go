@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// cursor walks the tokens of the scanner's current line. A trailing comment
+// is taken off the end of the line before parsing starts, and kept in comment.
+type cursor struct {
+	line string
+	start Pos
+	toks []lexer.Token
+	i    int
+	comment string
+}
+
+func newCursor(input Input) *cursor {
+	start := input.Pos()
+	c := &cursor{line: input.Text(), start: start, toks: lexer.LexAt(input.Text(), start)}
+	if n := len(c.toks); n > 1 && c.toks[n-2].Kind == lexer.Comment {
+		c.comment = commentText(c.toks[n-2].Text)
+		c.toks = append(c.toks[:n-2], c.toks[n-1])
+	}
+	return c
+}
+
+func (c *cursor) peek() lexer.Token {
+	return c.toks[c.i]
+}
+
+func (c *cursor) next() lexer.Token {
+	tok := c.toks[c.i]
+	if tok.Kind != lexer.EOF {
+		c.i += 1
+	}
+	return tok
+}
+
+// joined reports whether the next token directly follows the previous one,
+// with no space in between.
+func (c *cursor) joined() bool {
+	return c.i > 0 && c.peek().Pos.Offset == c.toks[c.i-1].End().Offset
+}
+
+func (c *cursor) done() bool {
+	return c.peek().Kind == lexer.EOF
+}
+
+func (c *cursor) accept(kind lexer.Kind) bool {
+	if c.peek().Kind == kind {
+		c.next()
+		return true
+	}
+	return false
+}
+
+// illegal reports whether any token on the line failed to lex.
+func (c *cursor) illegal() bool {
+	for _, tok := range c.toks {
+		if tok.Kind == lexer.Illegal {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the source text covered by tokens [from, to), with escaped
+// #s replaced by plain ones.
+func (c *cursor) text(from, to int) string {
+	if from >= to {
+		return ""
+	}
+	base := c.start.Offset
+	out := ""
+	for i := from; i < to; i++ {
+		tok := c.toks[i]
+		if i > from {
+			out += c.line[c.toks[i-1].End().Offset-base : tok.Pos.Offset-base]
+		}
+		if tok.Kind == lexer.Other && tok.Text == `\#` {
+			out += "#"
+		} else {
+			out += tok.Text
+		}
+	}
+	return out
+}
+
+// escapeComments is the inverse of text. It escapes each # outside of a
+// string literal so that it isn't read back as the start of a comment.
+func escapeComments(value string) string {
+	if !strings.Contains(value, "#") {
+		return value
+	}
+	out := ""
+	var quote rune
+	for _, r := range value {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '\'' || r == '"'):
+			quote = r
+		case quote == 0 && r == '#':
+			out += `\`
+		}
+		out += string(r)
+	}
+	return out
+}
+
+// span returns the source range of tokens [from, to). An empty range is
+// placed at the next token.
+func (c *cursor) span(from, to int) Span {
+	if from >= to {
+		return Span{Start: c.toks[from].Pos, End: c.toks[from].Pos}
+	}
+	return Span{Start: c.toks[from].Pos, End: c.toks[to-1].End()}
+}
+
+// lineSpan returns the source range of the whole line, without surrounding
+// whitespace.
+func (c *cursor) lineSpan() Span {
+	return c.span(0, len(c.toks)-1)
+}
+
+// word consumes a run of tokens that are not separated by whitespace and
+// returns their combined text. If kinds are given, the run stops at the first
+// token of any other kind.
+func (c *cursor) word(kinds ...lexer.Kind) string {
+	start := c.i
+	for !c.done() {
+		tok := c.peek()
+		if c.i > start && tok.Pos.Offset != c.toks[c.i-1].End().Offset {
+			break
+		}
+		if len(kinds) > 0 && !hasKind(kinds, tok.Kind) {
+			break
+		}
+		c.next()
+	}
+	return c.text(start, c.i)
+}
+
+// until consumes tokens up to, but not including, the first token of the
+// given kind, and returns the source text they cover.
+func (c *cursor) until(kind lexer.Kind) string {
+	start := c.i
+	for !c.done() && c.peek().Kind != kind {
+		c.next()
+	}
+	return c.text(start, c.i)
+}
+
+// rest consumes the remainder of the line and returns its source text.
+func (c *cursor) rest() string {
+	return c.until(lexer.EOF)
+}
+
+// restIndented is rest, but keeps the whitespace before the first remaining
+// token, less the single space that normally follows the previous one.
+func (c *cursor) restIndented() string {
+	if c.i == 0 || c.done() {
+		return c.rest()
+	}
+	base := c.start.Offset
+	indent := c.line[c.toks[c.i-1].End().Offset-base : c.peek().Pos.Offset-base]
+	return strings.TrimPrefix(indent, " ") + c.rest()
+}
+
+func hasKind(kinds []lexer.Kind, kind lexer.Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Dialect is the SQL dialect of the database a schema targets.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+	SQLite
+)
+
+var dialectNames = [...]string{
+	Postgres: "postgres",
+	MySQL:    "mysql",
+	SQLite:   "sqlite",
+}
+
+func (d Dialect) String() string {
+	if d < 0 || int(d) >= len(dialectNames) {
+		return "Dialect(?)"
+	}
+	return dialectNames[d]
+}
+
+// dialectAliases maps the names a `#database` directive may use to their
+// dialect.
+var dialectAliases = map[string]Dialect{
+	"postgres":   Postgres,
+	"postgresql": Postgres,
+	"pg":         Postgres,
+	"mysql":      MySQL,
+	"mariadb":    MySQL,
+	"sqlite":     SQLite,
+	"sqlite3":    SQLite,
+}
+
+// ParseDialect returns the dialect with the given name, ignoring case.
+func ParseDialect(name string) (Dialect, bool) {
+	d, ok := dialectAliases[strings.ToLower(name)]
+	return d, ok
+}
+
+// Dialect returns the dialect named by the `#database` directive. It reports
+// false if the directive isn't set or names an unknown database.
+func (p *ParseTree) Dialect() (Dialect, bool) {
+	db := p.Directive("database")
+	if db == nil {
+		return 0, false
+	}
+	return ParseDialect(db.Value())
+}
+
+// sqlString quotes s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlList quotes each value and joins them with commas.
+func sqlList(values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, sqlString(v))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// CreateSQL returns the statement that declares the enum as a type, for
+// dialects that have enum types. Other dialects return "", and enforce the
+// enum with CheckSQL instead.
+func (e *Enum) CreateSQL(d Dialect) string {
+	if d != Postgres {
+		return ""
+	}
+	return "CREATE TYPE " + e.Name + " AS ENUM (" + sqlList(e.Values()) + ");"
+}
+
+// ColumnSQL returns the SQL type of a column holding the enum.
+func (e *Enum) ColumnSQL(d Dialect) string {
+	switch d {
+	case Postgres:
+		return e.Name
+	case MySQL:
+		return "ENUM(" + sqlList(e.Values()) + ")"
+	}
+	return "TEXT"
+}
+
+// CheckSQL returns a CHECK constraint limiting column to the enum's values,
+// for dialects whose column type doesn't already do so. Other dialects
+// return "".
+func (e *Enum) CheckSQL(d Dialect, column string) string {
+	if d != SQLite {
+		return ""
+	}
+	return "CHECK (" + column + " IN (" + sqlList(e.Values()) + "))"
+}
+
+// scalarTypes maps the built-in scalar types to their SQL type in each
+// dialect. A type argument, if there is one, is added to the SQL type.
+var scalarTypes = map[string][3]string{
+	"int":     {Postgres: "INTEGER", MySQL: "INT", SQLite: "INTEGER"},
+	"string":  {Postgres: "TEXT", MySQL: "TEXT", SQLite: "TEXT"},
+	"bool":    {Postgres: "BOOLEAN", MySQL: "BOOLEAN", SQLite: "INTEGER"},
+	"float":   {Postgres: "DOUBLE PRECISION", MySQL: "DOUBLE", SQLite: "REAL"},
+	"decimal": {Postgres: "NUMERIC", MySQL: "DECIMAL", SQLite: "NUMERIC"},
+	"time":    {Postgres: "TIMESTAMP", MySQL: "DATETIME", SQLite: "TEXT"},
+}
+
+// scalarArgs is how many arguments each built-in scalar type takes: a length
+// for string, a precision and scale for decimal, and a precision for the
+// fractions of a second for time.
+var scalarArgs = map[string]int{
+	"int":     0,
+	"string":  1,
+	"bool":    0,
+	"float":   0,
+	"decimal": 2,
+	"time":    1,
+}
+
+// SQL returns the SQL type for t in dialect d. Collections are native
+// arrays on Postgres, and JSON elsewhere, stored as TEXT on SQLite. Types
+// with no known mapping are returned as written. That includes enums, which
+// ParseTree.ColumnSQL maps.
+func (t *TypeRef) SQL(d Dialect) string {
+	return t.sql(d, nil)
+}
+
+// sql is SQL, with enum looking up the enum a type name stands for, if
+// enum isn't nil.
+func (t *TypeRef) sql(d Dialect, enum func(string) *Enum) string {
+	if t.Elem != nil {
+		switch d {
+		case Postgres:
+			return t.Elem.sql(d, enum) + "[]"
+		case MySQL:
+			return "JSON"
+		}
+		return "TEXT"
+	}
+	if enum != nil && t.Args == nil {
+		if e := enum(t.Name); e != nil {
+			return e.ColumnSQL(d)
+		}
+	}
+	sql, ok := scalarTypes[t.Name]
+	if !ok {
+		return typeString(t.Name, t.Args)
+	}
+	args := t.Args
+	if len(args) > scalarArgs[t.Name] || t.Name == "time" && d == SQLite {
+		// SQLite keeps times as text, which has no precision
+		args = nil
+	}
+	if t.Name == "string" && args != nil {
+		return typeString("VARCHAR", args)
+	}
+	return typeString(sql[d], args)
+}
+
+// SQLType returns the column's SQL type in dialect d: the requested type
+// for d if there is one, and otherwise the mapping of its TypeRef. Columns
+// of enum types need ParseTree.ColumnSQL instead.
+func (c *Column) SQLType(d Dialect) string {
+	return c.sqlType(d, nil)
+}
+
+// ColumnSQL returns the SQL type of col in dialect d. It is col.SQLType,
+// except that the tree's enums, and collections of them, map as
+// Enum.ColumnSQL has them.
+func (p *ParseTree) ColumnSQL(col *Column, d Dialect) string {
+	return col.sqlType(d, p.Enum)
+}
+
+func (c *Column) sqlType(d Dialect, enum func(string) *Enum) string {
+	if requested := projectType(c.RequestedType, c.DialectTypes, d); requested != "" {
+		return requested
+	}
+	if c.TypeRef != nil {
+		return c.TypeRef.sql(d, enum)
+	}
+	return (&TypeRef{Name: c.Type, Args: c.TypeArgs}).sql(d, enum)
+}
@@ -0,0 +1,281 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// Directive is a `#name = value` line. The value is a comma-separated list
+// of items, each a word or a quoted string, optionally followed by
+// @version. Names can be dotted to group related settings.
+//
+//	#language = go, typescript
+//	#database = postgres@15
+//	#go.package = models
+type Directive struct {
+	Name string
+	Items []*DirectiveItem
+	// Raw is the value exactly as written.
+	Raw string
+	// Comment is the trailing comment on the directive line.
+	Comment string
+	Span Span
+}
+
+type DirectiveItem struct {
+	// Value is the item with any quotes and escapes removed.
+	Value string
+	Version string
+	Quoted bool
+}
+
+// String formats the item as it would be written in a directive.
+func (i *DirectiveItem) String() string {
+	out := i.Value
+	if i.Quoted || needsQuotes(i.Value) {
+		out = lexer.Quote(i.Value, '"')
+	}
+	if i.Version != "" {
+		out += "@" + i.Version
+	}
+	return out
+}
+
+// needsQuotes reports whether v would be read back as something other than
+// a single directive item.
+func needsQuotes(v string) bool {
+	if v == "" || strings.Contains(v, `\`) {
+		return true
+	}
+	toks := lexer.Lex(v)
+	for i, tok := range toks[:len(toks)-1] {
+		switch tok.Kind {
+		case lexer.String, lexer.Comment, lexer.Illegal, lexer.Comma, lexer.At:
+			return true
+		}
+		if i > 0 && tok.Pos.Offset != toks[i-1].End().Offset {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Directive) String() string {
+	var items []string
+	for _, item := range d.Items {
+		items = append(items, item.String())
+	}
+	return "#" + d.Name + "=" + strings.Join(items, ", ") + commentString(d.Comment)
+}
+
+// Value returns the value of the first item. Most directives only have one.
+func (d *Directive) Value() string {
+	if len(d.Items) == 0 {
+		return ""
+	}
+	return d.Items[0].Value
+}
+
+// Version returns the version of the first item, or "" if it has none.
+func (d *Directive) Version() string {
+	if len(d.Items) == 0 {
+		return ""
+	}
+	return d.Items[0].Version
+}
+
+// Values returns the value of every item, in order.
+func (d *Directive) Values() []string {
+	var out []string
+	for _, item := range d.Items {
+		out = append(out, item.Value)
+	}
+	return out
+}
+
+// Key returns the parts of a dotted name, so `#go.package` gives
+// ["go", "package"].
+func (d *Directive) Key() []string {
+	return strings.Split(d.Name, ".")
+}
+
+// sameValue reports whether d and e set the same items.
+func (d *Directive) sameValue(e *Directive) bool {
+	if len(d.Items) != len(e.Items) {
+		return false
+	}
+	for i, item := range d.Items {
+		if item.Value != e.Items[i].Value || item.Version != e.Items[i].Version {
+			return false
+		}
+	}
+	return true
+}
+
+// Directive returns the directive with the given name, or nil if it isn't
+// set. If it is set more than once, the last definition wins.
+func (p *ParseTree) Directive(name string) *Directive {
+	for i := len(p.Directives) - 1; i >= 0; i-- {
+		if p.Directives[i].Name == name {
+			return p.Directives[i]
+		}
+	}
+	return nil
+}
+
+// DirectivesUnder returns the directives nested under prefix, such as
+// `#go.package` and `#go.module` for "go", in source order. Only the last
+// definition of each is included.
+func (p *ParseTree) DirectivesUnder(prefix string) []*Directive {
+	var out []*Directive
+	for _, d := range p.Directives {
+		if strings.HasPrefix(d.Name, prefix+".") && p.Directive(d.Name) == d {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// ParseDirective reads a line of the form `#name = value`. A line starting
+// with # that doesn't have that shape is a comment, and reports false.
+func ParseDirective(input Input) (*Directive, bool) {
+	input.Scan()
+	c := newCursor(input)
+	if c.illegal() || !c.accept(lexer.Hash) {
+		return nil, false
+	}
+	d := &Directive{Comment: c.comment, Span: c.lineSpan()}
+	d.Name = c.word(lexer.Ident, lexer.Dash, lexer.Dot)
+	if !validKey(d.Name) || !c.accept(lexer.Equals) {
+		return nil, false
+	}
+	start := c.i
+	for {
+		item, ok := parseDirectiveItem(c)
+		if !ok {
+			return nil, false
+		}
+		d.Items = append(d.Items, item)
+		if !c.accept(lexer.Comma) {
+			break
+		}
+	}
+	if !c.done() {
+		return nil, false
+	}
+	d.Raw = c.text(start, c.i)
+	return d, true
+}
+
+// knownDirectives are the directive names in common use. A comment starting
+// with one of them was most likely meant to set it.
+var knownDirectives = map[string]bool{
+	"include": true,
+	"language": true,
+	"database": true,
+	"driver": true,
+	"schema": true,
+}
+
+// checkDirective looks at a comment line for signs that it was meant to be a
+// directive: a known directive name, or a name followed by = or :. It
+// returns a warning, with a corrected line where one can be made, or nil if
+// the line reads as an ordinary comment.
+//
+// Names that aren't known only count when written right after the #, so
+// that prose like `# Note: ...` isn't flagged, and with : only when the
+// value is a single directive item, so that `#TODO: fix this` isn't either.
+func checkDirective(input Input) *Warning {
+	c := newCursor(input)
+	if c.illegal() || !c.accept(lexer.Hash) {
+		return nil
+	}
+	attached := c.peek().Pos.Offset == c.toks[0].End().Offset
+	name := c.word(lexer.Ident, lexer.Dash, lexer.Dot)
+	if !validKey(name) || !knownDirectives[name] && !attached {
+		return nil
+	}
+
+	w := &Warning{Span: c.lineSpan()}
+	switch {
+	case c.accept(lexer.Equals):
+		w.Msg = "Directive " + name + " has a value that can't be read"
+	case c.accept(lexer.Colon):
+		if !knownDirectives[name] && !singleItem(name, strings.TrimSpace(c.text(c.i, len(c.toks)-1))) {
+			return nil
+		}
+		w.Msg = "Directive " + name + " uses : instead of ="
+	case knownDirectives[name] && attached && !c.done():
+		w.Msg = "Directive " + name + " is missing ="
+	default:
+		return nil
+	}
+	w.Fix = directiveFix(name, strings.TrimSpace(c.rest()))
+	return w
+}
+
+// singleItem reports whether value, as written, reads as a directive with
+// one item.
+func singleItem(name, value string) bool {
+	d, ok := ParseDirective(NewScanner(strings.NewReader("#" + name + " = " + value)))
+	return ok && len(d.Items) == 1
+}
+
+// directiveFix suggests a directive line setting name to value, quoting the
+// value if it can't be read as it is.
+func directiveFix(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	for _, fix := range []string{
+		"#" + name + " = " + value,
+		"#" + name + " = " + lexer.Quote(value, '"'),
+	} {
+		if _, ok := ParseDirective(NewScanner(strings.NewReader(fix))); ok {
+			return fix
+		}
+	}
+	return ""
+}
+
+func validKey(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// An item is a word or a quoted string, optionally followed by @version.
+func parseDirectiveItem(c *cursor) (*DirectiveItem, bool) {
+	item := &DirectiveItem{}
+	if c.peek().Kind == lexer.String {
+		value, err := lexer.Unquote(c.next().Text)
+		if err != nil {
+			return nil, false
+		}
+		item.Value = value
+		item.Quoted = true
+	} else {
+		item.Value = c.word(valueKinds...)
+		if item.Value == "" {
+			return nil, false
+		}
+	}
+	if c.accept(lexer.At) {
+		item.Version = c.word(valueKinds...)
+		if item.Version == "" {
+			return nil, false
+		}
+	}
+	return item, true
+}
+
+// valueKinds are the tokens that can make up an unquoted directive item.
+var valueKinds = []lexer.Kind{
+	lexer.Ident, lexer.Dash, lexer.Dot, lexer.Colon, lexer.Equals,
+	lexer.LBracket, lexer.RBracket, lexer.Hash, lexer.Using, lexer.Pipe, lexer.Bang,
+	lexer.LParen, lexer.RParen, lexer.Other,
+}
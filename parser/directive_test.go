@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var directiveItemsTests = []struct {
+	name string
+	input string
+	expected Directive
+}{
+	{"Single Value", "#language = go", Directive{Name: "language", Items: []*DirectiveItem{
+		{Value: "go"},
+	}}},
+	{"List", "#language = go, typescript", Directive{Name: "language", Items: []*DirectiveItem{
+		{Value: "go"},
+		{Value: "typescript"},
+	}}},
+	{"List Without Spaces", "#language=go,typescript,ruby", Directive{Name: "language", Items: []*DirectiveItem{
+		{Value: "go"},
+		{Value: "typescript"},
+		{Value: "ruby"},
+	}}},
+	{"Version", "#database = postgres@15", Directive{Name: "database", Items: []*DirectiveItem{
+		{Value: "postgres", Version: "15"},
+	}}},
+	{"Dotted Version", "#database = postgres@15.2", Directive{Name: "database", Items: []*DirectiveItem{
+		{Value: "postgres", Version: "15.2"},
+	}}},
+	{"Versioned List", "#database = postgres@15, sqlite@3", Directive{Name: "database", Items: []*DirectiveItem{
+		{Value: "postgres", Version: "15"},
+		{Value: "sqlite", Version: "3"},
+	}}},
+	{"Nested Key", "#go.package = models", Directive{Name: "go.package", Items: []*DirectiveItem{
+		{Value: "models"},
+	}}},
+	{"Quoted Items", `#include = "my schemas/a.orb", b.orb`, Directive{Name: "include", Items: []*DirectiveItem{
+		{Value: "my schemas/a.orb", Quoted: true},
+		{Value: "b.orb"},
+	}}},
+	{"Quoted With Version", `#generator = "orb gen"@2`, Directive{Name: "generator", Items: []*DirectiveItem{
+		{Value: "orb gen", Version: "2", Quoted: true},
+	}}},
+}
+
+func TestParseDirectiveItems(t *testing.T) {
+	for _, test := range directiveItemsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			d, ok := ParseDirective(DummyScanner(test.input))
+			if !ok {
+				tt.Fatalf("Directive not recognised: %s", test.input)
+			}
+			if !d.Equals(test.expected) {
+				tt.Fatalf("Incorrect directive. Expected %s; got %s", &test.expected, d)
+			}
+			again, ok := ParseDirective(DummyScanner(d.String()))
+			if !ok || !again.Equals(test.expected) {
+				tt.Fatalf("Directive not preserved by String: %s", d)
+			}
+		})
+	}
+}
+
+var directiveItemsRejectsTests = []struct {
+	name string
+	input string
+}{
+	{"Empty Item", "#language = go,,ruby"},
+	{"Trailing Comma", "#language = go,"},
+	{"Missing Version", "#database = postgres@"},
+	{"Empty Key Part", "#go..package = models"},
+	{"Leading Dot", "#.package = models"},
+}
+
+func TestParseDirectiveItemsRejects(t *testing.T) {
+	for _, test := range directiveItemsRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			if d, ok := ParseDirective(DummyScanner(test.input)); ok {
+				tt.Fatalf("Directive match not expected for %s; got %s", test.input, d)
+			}
+		})
+	}
+}
+
+func TestDirectiveAccessors(t *testing.T) {
+	input := "#language = go, typescript # targets\n#database = postgres@15\n#go.package = models\n#go.module = example.com/app"
+	tree, errs := Parse(strings.NewReader(input))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	language := tree.Directive("language")
+	if strings.Join(language.Values(), "|") != "go|typescript" || language.Raw != "go, typescript" || language.Comment != "targets" {
+		t.Fatalf("Incorrect list directive: %+v", language)
+	}
+	if db := tree.Directive("database"); db.Value() != "postgres" || db.Version() != "15" {
+		t.Fatalf("Incorrect versioned directive: %s", db)
+	}
+	var names []string
+	for _, d := range tree.DirectivesUnder("go") {
+		names = append(names, strings.Join(d.Key(), "/")+"="+d.Value())
+	}
+	if strings.Join(names, " ") != "go/package=models go/module=example.com/app" {
+		t.Fatalf("Incorrect nested directives: %v", names)
+	}
+	if tree.Directive("missing") != nil {
+		t.Fatalf("Unset directive should be nil")
+	}
+}
+
+func TestDirectiveOrder(t *testing.T) {
+	input := "#language = go\n#database = postgres\n#driver = pq\n#database = postgres\n#another = one"
+	for i := 0; i < 20; i++ {
+		tree, errs := Parse(strings.NewReader(input))
+		if errs != nil {
+			t.Fatalf("Unexpected errors: %v", errs)
+		}
+		expected := "#language=go\n#database=postgres\n#driver=pq\n#database=postgres\n#another=one\n"
+		if tree.String() != expected {
+			t.Fatalf("Directives out of order. Expected:\n%s\ngot:\n%s", expected, tree)
+		}
+	}
+}
+
+var directiveConflictTests = []struct {
+	name string
+	input string
+	value string
+	conflicts int
+}{
+	{"Same Value Repeated", "#database = postgres\n#database=postgres", "postgres", 0},
+	{"Different Value", "#database = postgres\n#database = sqlite", "sqlite", 1},
+	{"Different Version", "#database = postgres@14\n#database = postgres@15", "postgres", 1},
+	{"Different List", "#database = postgres\n#database = postgres, sqlite", "postgres", 1},
+	{"Each Redefinition", "#database = a\n#database = b\n#database = c", "c", 2},
+}
+
+func TestDirectiveConflicts(t *testing.T) {
+	for _, test := range directiveConflictTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := ParseNamed("db.orb", strings.NewReader(test.input))
+			if len(errs) != test.conflicts {
+				tt.Fatalf("Expected %d errors; got %v", test.conflicts, errs)
+			}
+			for _, err := range errs {
+				if !strings.Contains(err.Error(), "redefined") || !strings.HasPrefix(err.Error(), "db.orb:") {
+					tt.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if d := tree.Directive("database"); d.Value() != test.value {
+				tt.Fatalf("Incorrect effective value. Expected %s; got %s", test.value, d)
+			}
+		})
+	}
+}
+
+var directiveWarningsTests = []struct {
+	name string
+	input string
+	fix string
+}{
+	{"Colon Separator", "#database:postgres", "#database = postgres"},
+	{"Spaced Colon Separator", "# database: postgres # main db", "#database = postgres"},
+	{"Missing Separator", "#database postgres@15", "#database = postgres@15"},
+	{"Multiple Words", "#directive=multiple words", `#directive = "multiple words"`},
+	{"Unknown Name With Colon", "#holyeggbeaters:batman", "#holyeggbeaters = batman"},
+	{"No Value", "#database:", ""},
+}
+
+func TestDirectiveWarnings(t *testing.T) {
+	for _, test := range directiveWarningsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := ParseNamed("db.orb", strings.NewReader(test.input+"\n[a]\nid int"))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if len(tree.Warnings) != 1 {
+				tt.Fatalf("Expected one warning; got %v", tree.Warnings)
+			}
+			w := tree.Warnings[0]
+			if w.Fix != test.fix || !strings.HasPrefix(w.String(), "db.orb:1:1:") {
+				tt.Fatalf("Incorrect warning. Expected fix %q; got %s", test.fix, w)
+			}
+			if test.fix == "" {
+				return
+			}
+			fixed, errs := Parse(strings.NewReader(test.fix))
+			if errs != nil || len(fixed.Directives) != 1 || fixed.Warnings != nil {
+				tt.Fatalf("Suggested fix doesn't parse as a directive: %s", test.fix)
+			}
+		})
+	}
+}
+
+var directiveNoWarningsTests = []struct {
+	name string
+	input string
+}{
+	{"Prose", "# Users of the system"},
+	{"Prose With Colon", "# Note: ids are never reused"},
+	{"Todo Note", "#TODO: fix this"},
+	{"Attached Note", "#NOTE: see the billing schema"},
+	{"Known Name In Prose", "# database tables below"},
+	{"Bare Known Name", "#database"},
+	{"Valid Directive", "#database = postgres"},
+	{"Commented Out Directive", "# #database = postgres"},
+}
+
+func TestDirectiveNoWarnings(t *testing.T) {
+	for _, test := range directiveNoWarningsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(test.input))
+			if errs != nil || tree.Warnings != nil {
+				tt.Fatalf("Unexpected diagnostics: %v %v", errs, tree.Warnings)
+			}
+		})
+	}
+}
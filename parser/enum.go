@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// Enum is a closed set of values, declared in its own block:
+//
+//	[enum status]
+//	active
+//	inactive = 'gone'
+//
+// Its name can be used as a column type. Each member is stored as its
+// explicit value if it has one, and as its name otherwise.
+type Enum struct {
+	Name string
+	// Doc is the text of the comment lines directly above the header.
+	Doc string
+	// Comment is the trailing comment on the header line.
+	Comment string
+	Members []*EnumMember
+	Span Span
+	// Bad holds member lines that couldn't be parsed.
+	Bad []*BadLine
+}
+
+type EnumMember struct {
+	Name string
+	// Value is the explicit value, unquoted, or "" if the member has none.
+	Value string
+	Quoted bool
+	// Doc is the text of the comment lines directly above the member.
+	Doc string
+	// Comment is the trailing comment on the member line.
+	Comment string
+	Span Span
+}
+
+func (e *Enum) String() string {
+	s := docString(e.Doc) + "[enum " + e.Name + "]" + commentString(e.Comment)
+	for _, m := range e.Members {
+		s += "\n" + m.String()
+	}
+	return s + "\n"
+}
+
+func (m *EnumMember) String() string {
+	s := docString(m.Doc) + m.Name
+	if m.Quoted || m.Value != "" && needsQuotes(m.Value) {
+		s += " = " + lexer.Quote(m.Value, '\'')
+	} else if m.Value != "" {
+		s += " = " + m.Value
+	}
+	return s + commentString(m.Comment)
+}
+
+// Stored returns the value the member is stored as.
+func (m *EnumMember) Stored() string {
+	if m.Value != "" || m.Quoted {
+		return m.Value
+	}
+	return m.Name
+}
+
+// Values returns the stored value of every member, in order.
+func (e *Enum) Values() []string {
+	var out []string
+	for _, m := range e.Members {
+		out = append(out, m.Stored())
+	}
+	return out
+}
+
+// Enum returns the enum with the given name, or nil if there is none.
+func (p *ParseTree) Enum(name string) *Enum {
+	for _, e := range p.Enums {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// isEnumHeader reports whether a line starting with [ opens an enum rather
+// than a table. A table can still be called enum.
+func isEnumHeader(c *cursor) bool {
+	return len(c.toks) > 3 && keyword(c.toks[1], "enum") && c.toks[2].Kind == lexer.Ident
+}
+
+// ParseEnum reads an enum header and its members, up to a blank line or the
+// next header. Members that fail to parse are recorded in Enum.Bad. If the
+// header itself is invalid, the whole block is skipped and no enum is
+// returned.
+func ParseEnum(input Input) (*Enum, []error) {
+	input.Scan()
+	c := newCursor(input)
+	var name string
+	if c.accept(lexer.LBracket) && keyword(c.peek(), "enum") {
+		c.next()
+		name = c.word(lexer.Ident, lexer.Dash)
+	}
+	if name == "" || !c.accept(lexer.RBracket) || !c.done() {
+		err := NewError("Invalid enum name", input)
+		skipBlock(input)
+		return nil, []error{err}
+	}
+
+	enum := &Enum{Name: name, Comment: c.comment, Span: c.lineSpan()}
+	var errors []error
+	var doc []string
+	for input.Scan() {
+		switch newCursor(input).peek().Kind {
+		case lexer.EOF:
+			return enum, errors
+		case lexer.Hash:
+			doc = append(doc, commentText(input.Text()))
+			continue
+		case lexer.LBracket:
+			input.Backtrack()
+			return enum, errors
+		}
+		member, err := parseEnumMember(input)
+		if err == nil {
+			if prev := enum.member(member.Name); prev != nil {
+				err = errorAt("Enum member "+member.Name+" is declared more than once", member.Span.Start)
+			} else if prev := enum.stored(member.Stored()); prev != nil {
+				err = errorAt("Enum members "+prev.Name+" and "+member.Name+" have the same value", member.Span.Start)
+			}
+		}
+		if err != nil {
+			errors = append(errors, err)
+			enum.Bad = append(enum.Bad, newBadLine(input, err))
+		} else {
+			member.Doc = strings.Join(doc, "\n")
+			enum.Members = append(enum.Members, member)
+			enum.Span.End = member.Span.End
+		}
+		doc = nil
+	}
+	return enum, errors
+}
+
+// A member line is `name` or `name = value`, where value is a word or a
+// quoted string.
+func parseEnumMember(input Input) (*EnumMember, error) {
+	c := newCursor(input)
+	m := &EnumMember{Comment: c.comment, Span: c.lineSpan()}
+	name := c.peek()
+	if c.illegal() || !c.accept(lexer.Ident) {
+		return nil, NewError("Invalid enum member", input)
+	}
+	m.Name = name.Text
+	if c.accept(lexer.Equals) {
+		value := c.peek()
+		if value.Kind == lexer.String {
+			c.next()
+			unquoted, err := lexer.Unquote(value.Text)
+			if err != nil {
+				return nil, errorAt("Invalid escape sequence in string literal", value.Pos)
+			}
+			m.Value = unquoted
+			m.Quoted = true
+		} else if m.Value = c.word(valueKinds...); m.Value == "" {
+			return nil, errorAt("Missing enum value", value.Pos)
+		}
+	}
+	if !c.done() {
+		return nil, errorAt("Unexpected text after enum member", c.peek().Pos)
+	}
+	return m, nil
+}
+
+func (e *Enum) member(name string) *EnumMember {
+	for _, m := range e.Members {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func (e *Enum) stored(value string) *EnumMember {
+	for _, m := range e.Members {
+		if m.Stored() == value {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var enumMatchesTests = []struct {
+	name string
+	input string
+	expected ParseTree
+}{
+	{"Names Only", "[enum status]\nactive\ninactive", ParseTree{Enums: []*Enum{
+		{Name: "status", Members: []*EnumMember{{Name: "active"}, {Name: "inactive"}}},
+	}}},
+	{"Explicit Values", "[enum status]\nactive = 'A'\ninactive = gone\npending = 3", ParseTree{Enums: []*Enum{
+		{Name: "status", Members: []*EnumMember{
+			{Name: "active", Value: "A", Quoted: true},
+			{Name: "inactive", Value: "gone"},
+			{Name: "pending", Value: "3"},
+		}},
+	}}},
+	{"Docs And Comments", "# Account states\n[enum status] # see billing\n# still paying\nactive\ninactive # closed", ParseTree{Enums: []*Enum{
+		{Name: "status", Doc: "Account states", Comment: "see billing", Members: []*EnumMember{
+			{Name: "active", Doc: "still paying"},
+			{Name: "inactive", Comment: "closed"},
+		}},
+	}}},
+	{"Alongside Tables", "[enum status]\nactive\n[users]\nstate status\n\n[enum]\nid int", ParseTree{
+		Enums: []*Enum{
+			{Name: "status", Members: []*EnumMember{{Name: "active"}}},
+		},
+		Tables: []*Table{
+			{Name: "users", Columns: []*Column{{Name: "state", Type: "status"}}},
+			{Name: "enum", Columns: []*Column{{Name: "id", Type: "int"}}},
+		},
+	}},
+	{"Value Needing Quotes", "[enum mood]\nhappy = 'very happy'\nsharp = '#'", ParseTree{Enums: []*Enum{
+		{Name: "mood", Members: []*EnumMember{
+			{Name: "happy", Value: "very happy", Quoted: true},
+			{Name: "sharp", Value: "#", Quoted: true},
+		}},
+	}}},
+}
+
+func TestParseEnumMatches(t *testing.T) {
+	for _, test := range enumMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !tree.Equals(test.expected) {
+				tt.Fatalf("Incorrect tree. Expected:\n%s\ngot:\n%s", &test.expected, tree)
+			}
+			again, errs := Parse(strings.NewReader(tree.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Tree not preserved by String:\n%s", tree)
+			}
+		})
+	}
+}
+
+var enumRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Invalid Name", "[enum two words]\nactive", "1:1:Invalid enum name"},
+	{"Invalid Member", "[enum status]\nactive\n- NOT NULL", "3:1:Invalid enum member"},
+	{"Missing Value", "[enum status]\nactive =", "2:9:Missing enum value"},
+	{"Trailing Text", "[enum status]\nactive = 'a' 'b'", "2:14:Unexpected text after enum member"},
+	{"Repeated Member", "[enum status]\nactive\nactive = 'b'", "3:1:Enum member active is declared more than once"},
+	{"Repeated Value", "[enum status]\nactive\nlive = active", "3:1:Enum members active and live have the same value"},
+	{"Repeated Enum", "[enum status]\nactive\n\n[enum status]\nlive", "4:1:Enum status is already declared at 1:1"},
+}
+
+func TestParseEnumRejects(t *testing.T) {
+	for _, test := range enumRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := Parse(strings.NewReader(test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+var enumSQLTests = []struct {
+	dialect Dialect
+	create string
+	column string
+	check string
+}{
+	{Postgres, "CREATE TYPE status AS ENUM ('active', 'it''s gone');", "status", ""},
+	{MySQL, "", "ENUM('active', 'it''s gone')", ""},
+	{SQLite, "", "TEXT", "CHECK (state IN ('active', 'it''s gone'))"},
+}
+
+func TestEnumSQL(t *testing.T) {
+	tree, errs := Parse(strings.NewReader("[enum status]\nactive\ninactive = \"it's gone\""))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	status := tree.Enum("status")
+	for _, test := range enumSQLTests {
+		t.Run(test.dialect.String(), func(tt *testing.T) {
+			if got := status.CreateSQL(test.dialect); got != test.create {
+				tt.Errorf("Incorrect CreateSQL. Expected %q; got %q", test.create, got)
+			}
+			if got := status.ColumnSQL(test.dialect); got != test.column {
+				tt.Errorf("Incorrect ColumnSQL. Expected %q; got %q", test.column, got)
+			}
+			if got := status.CheckSQL(test.dialect, "state"); got != test.check {
+				tt.Errorf("Incorrect CheckSQL. Expected %q; got %q", test.check, got)
+			}
+		})
+	}
+}
+
+var enumColumnSQLTests = []struct {
+	dialect Dialect
+	state string
+	history string
+}{
+	{Postgres, "status", "status[]"},
+	{MySQL, "ENUM('active', 'gone')", "JSON"},
+	{SQLite, "TEXT", "TEXT"},
+}
+
+func TestEnumColumnSQL(t *testing.T) {
+	tree, errs := Parse(strings.NewReader("[enum status]\nactive\ngone\n\n[account]\nstate status\nhistory []status"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	account := tree.Table("account")
+	for _, test := range enumColumnSQLTests {
+		t.Run(test.dialect.String(), func(tt *testing.T) {
+			if got := tree.ColumnSQL(account.Column("state"), test.dialect); got != test.state {
+				tt.Errorf("Incorrect type of enum column. Expected %q; got %q", test.state, got)
+			}
+			if got := tree.ColumnSQL(account.Column("history"), test.dialect); got != test.history {
+				tt.Errorf("Incorrect type of enum list. Expected %q; got %q", test.history, got)
+			}
+		})
+	}
+}
+
+func TestTreeDialect(t *testing.T) {
+	for name, expected := range map[string]Dialect{"postgres": Postgres, "PostgreSQL": Postgres, "mariadb": MySQL, "sqlite3": SQLite} {
+		tree, _ := Parse(strings.NewReader("#database = " + name + "@3"))
+		if d, ok := tree.Dialect(); !ok || d != expected {
+			t.Fatalf("Incorrect dialect for %s: %v", name, d)
+		}
+	}
+	tree, _ := Parse(strings.NewReader("#database = oracle"))
+	if _, ok := tree.Dialect(); ok {
+		t.Fatalf("Unknown database should have no dialect")
+	}
+}
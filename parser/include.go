@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Include is an `#include = path` directive. The path is relative to the
+// directory of the file containing the directive.
+type Include struct {
+	Path string
+	Span Span
+}
+
+// ParseFile parses the named file along with every file it includes,
+// directly or indirectly, and merges them into one tree. Each file is read
+// once, however many times it is included, and include cycles are reported
+// as errors. Nodes keep the name of the file they came from in their spans.
+// References are resolved against the merged tree.
+//
+// Tables from included files come first, in include order, followed by the
+// tables of the including file. Directives set by the including file take
+// precedence over those from the files it includes.
+func ParseFile(filename string) (*ParseTree, []error) {
+	return ParseFileWithOptions(filename, Options{})
+}
+
+// ParseFileWithOptions is ParseFile with options applied to every file read.
+// The Filename option is ignored.
+func ParseFileWithOptions(filename string, opts Options) (*ParseTree, []error) {
+	inc := &includer{
+		open: func(name string) (io.ReadCloser, error) { return os.Open(name) },
+		opts: opts,
+		seen: map[string]bool{includeKey(filename): true},
+	}
+	tree, errs, err := inc.parse(filename)
+	if err != nil {
+		return NewParseTree(), []error{&ParseError{msg: "Cannot read file: " + err.Error(), pos: Pos{File: filename}, cause: err}}
+	}
+	return tree, append(errs, tree.Resolve()...)
+}
+
+type includer struct {
+	open func(name string) (io.ReadCloser, error)
+	opts Options
+	// seen holds every file that has been read, so each is merged only once
+	seen map[string]bool
+	// stack holds the chain of files currently being read, to detect cycles
+	stack []string
+}
+
+// includeKey identifies a file independently of how its path was written.
+func includeKey(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		return abs
+	}
+	return filepath.Clean(filename)
+}
+
+// parse reads one file and resolves its includes. The error is only set if
+// the file itself couldn't be opened.
+func (inc *includer) parse(filename string) (*ParseTree, []error, error) {
+	f, err := inc.open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	opts := inc.opts
+	opts.Filename = filename
+	// references are resolved once every file has been merged
+	tree, errs := parseInput(newInput(f, opts))
+
+	inc.stack = append(inc.stack, filename)
+	defer func() { inc.stack = inc.stack[:len(inc.stack)-1] }()
+
+	merged := NewParseTree()
+	for _, include := range tree.Includes {
+		path := include.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), path)
+		}
+		key := includeKey(path)
+		if cycle := inc.cycle(path); cycle != "" {
+			errs = append(errs, errorAt("Include cycle: "+cycle, include.Span.Start))
+			continue
+		}
+		if inc.seen[key] {
+			continue
+		}
+		inc.seen[key] = true
+
+		sub, subErrs, err := inc.parse(path)
+		if err != nil {
+			errs = append(errs, &ParseError{msg: "Cannot include " + include.Path + ": " + err.Error(), pos: include.Span.Start, cause: err})
+			continue
+		}
+		errs = append(errs, subErrs...)
+		merged.merge(sub)
+	}
+	merged.merge(tree)
+	return merged, errs, nil
+}
+
+// cycle returns the include chain leading back to filename, or "" if it
+// isn't already being read.
+func (inc *includer) cycle(filename string) string {
+	key := includeKey(filename)
+	for i, name := range inc.stack {
+		if includeKey(name) == key {
+			chain := append([]string{}, inc.stack[i:]...)
+			return strings.Join(append(chain, filename), " -> ")
+		}
+	}
+	return ""
+}
+
+// merge adds the contents of q to p. Directives from q come later, and so
+// override those in p, so merging included files before the including one
+// gives it precedence.
+func (p *ParseTree) merge(q *ParseTree) {
+	p.Directives = append(p.Directives, q.Directives...)
+	p.Includes = append(p.Includes, q.Includes...)
+	p.Tables = append(p.Tables, q.Tables...)
+	p.Mixins = append(p.Mixins, q.Mixins...)
+	p.Types = append(p.Types, q.Types...)
+	p.Views = append(p.Views, q.Views...)
+	p.Enums = append(p.Enums, q.Enums...)
+	p.Bad = append(p.Bad, q.Bad...)
+	p.Warnings = append(p.Warnings, q.Warnings...)
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the given files under a fresh directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func tableNames(tree *ParseTree) string {
+	var names []string
+	for _, t := range tree.Tables {
+		names = append(names, t.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+var includeMatchesTests = []struct {
+	name string
+	files map[string]string
+	tables string
+}{
+	{"No Includes", map[string]string{
+		"main.orb": "[a]\nid int",
+	}, "a"},
+	{"Single Include", map[string]string{
+		"main.orb": "#include = users.orb\n[a]\nid int",
+		"users.orb": "[users]\nid int",
+	}, "users,a"},
+	{"Include List", map[string]string{
+		"main.orb": "#include = users.orb, orders.orb\n[a]",
+		"users.orb": "[users]",
+		"orders.orb": "[orders]",
+	}, "users,orders,a"},
+	{"Relative To Including File", map[string]string{
+		"main.orb": "#include = billing/all.orb",
+		"billing/all.orb": "#include = invoice.orb\n[payment]",
+		"billing/invoice.orb": "[invoice]",
+	}, "invoice,payment"},
+	{"Parent Directory", map[string]string{
+		"main.orb": "#include = auth/users.orb",
+		"auth/users.orb": "#include = ../shared.orb\n[users]",
+		"shared.orb": "[shared]",
+	}, "shared,users"},
+	{"Repeated Include Read Once", map[string]string{
+		"main.orb": "#include = a.orb\n#include = b.orb\n#include = a.orb",
+		"a.orb": "#include = common.orb\n[a]",
+		"b.orb": "#include = ./common.orb\n[b]",
+		"common.orb": "[common]",
+	}, "common,a,b"},
+}
+
+func TestParseFileMatches(t *testing.T) {
+	for _, test := range includeMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			dir := writeFiles(tt, test.files)
+			tree, errs := ParseFile(filepath.Join(dir, "main.orb"))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if names := tableNames(tree); names != test.tables {
+				tt.Fatalf("Incorrect tables. Expected %s; got %s", test.tables, names)
+			}
+		})
+	}
+}
+
+var includeRejectsTests = []struct {
+	name string
+	files map[string]string
+	tables string
+	message string
+}{
+	{"Missing File", map[string]string{
+		"main.orb": "#include = missing.orb\n[a]",
+	}, "a", "Cannot include missing.orb"},
+	{"Self Include", map[string]string{
+		"main.orb": "#include = main.orb\n[a]",
+	}, "a", "Include cycle"},
+	{"Indirect Cycle", map[string]string{
+		"main.orb": "#include = a.orb\n[main]",
+		"a.orb": "#include = b.orb\n[a]",
+		"b.orb": "#include = a.orb\n[b]",
+	}, "b,a,main", "Include cycle"},
+}
+
+func TestParseFileRejects(t *testing.T) {
+	for _, test := range includeRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			dir := writeFiles(tt, test.files)
+			tree, errs := ParseFile(filepath.Join(dir, "main.orb"))
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.message) {
+				tt.Fatalf("Expected one %q error; got %v", test.message, errs)
+			}
+			if names := tableNames(tree); names != test.tables {
+				tt.Fatalf("Incorrect tables. Expected %s; got %s", test.tables, names)
+			}
+		})
+	}
+}
+
+func TestParseFileRecordsFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.orb": "#database = postgres\n#include = users.orb\n[a]\nid int",
+		"users.orb": "#database = sqlite\n#language = go\n[users]\nid int\n-primary key",
+	})
+	tree, errs := ParseFile(filepath.Join(dir, "main.orb"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	users := tree.Tables[0]
+	if file := users.Columns[0].Constraints[0].Span.Start.File; file != filepath.Join(dir, "users.orb") {
+		t.Fatalf("Included node has the wrong file: %s", file)
+	}
+	if file := tree.Tables[1].Span.Start.File; file != filepath.Join(dir, "main.orb") {
+		t.Fatalf("Including node has the wrong file: %s", file)
+	}
+	if tree.Directive("database").Value() != "postgres" || tree.Directive("language").Value() != "go" {
+		t.Fatalf("Incorrect merged directives: %v", tree.Directives)
+	}
+}
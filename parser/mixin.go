@@ -0,0 +1,224 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// Mixins let tables share columns. A mixin is declared like a table, but
+// with `mixin` before its name, and is never a table itself. A table lists
+// the mixins it uses after a colon in its header:
+//
+//	[mixin timestamps]
+//	created_at time
+//	updated_at time
+//
+//	[student : identified, timestamps]
+//	name string
+//
+// Resolving the tree copies the columns, table constraints and indexes of
+// each mixin into the tables that use it, ahead of the table's own, and
+// records where each copy came from in its From field. Mixins can use other
+// mixins. The rules for names that clash are:
+//
+//   - a column declared in the table replaces any inherited column of the
+//     same name, without a diagnostic;
+//   - if two mixins define the same column, the first one listed wins, and
+//     the clash is reported, unless both inherited it from the same mixin.
+
+// parseHeader reads a table header, `[name]`, `[namespace.name]`,
+// `[name : mixin, ...]` or `[mixin name ...]`. It reports false if the header is invalid.
+func parseHeader(c *cursor, table *Table) bool {
+	if !c.accept(lexer.LBracket) {
+		return false
+	}
+	name := c.word(lexer.Ident, lexer.Dash, lexer.Dot)
+	if name == "mixin" && c.peek().Kind == lexer.Ident {
+		table.Abstract = true
+		name = c.word(lexer.Ident, lexer.Dash)
+	}
+	var ok bool
+	if table.Namespace, table.Name, ok = splitTableName(name); !ok || table.Name == "" {
+		return false
+	}
+	if c.accept(lexer.Colon) {
+		for {
+			mixin := c.word(lexer.Ident, lexer.Dash)
+			if mixin == "" {
+				return false
+			}
+			table.Mixins = append(table.Mixins, mixin)
+			if !c.accept(lexer.Comma) {
+				break
+			}
+		}
+	}
+	return c.accept(lexer.RBracket) && c.done()
+}
+
+// headerString formats the header of t, for String methods.
+func (t *Table) headerString() string {
+	s := "[" + t.QualifiedName()
+	if t.defaultNamespace {
+		s = "[" + t.Name
+	}
+	if t.Abstract {
+		s = "[mixin " + t.Name
+	}
+	if t.Mixins != nil {
+		s += " : " + strings.Join(t.Mixins, ", ")
+	}
+	return s + "]"
+}
+
+// Mixin returns the mixin with the given name, or nil if there is none.
+func (p *ParseTree) Mixin(name string) *Table {
+	for _, m := range p.Mixins {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// resolveMixins applies the mixins of every table and mixin in the tree. If
+// partial is set, those that use an unknown mixin, directly or not, are
+// skipped, since it may be declared in a file that wasn't read.
+func (p *ParseTree) resolveMixins(partial bool) []error {
+	var errors []error
+	for _, m := range p.Mixins {
+		if first := p.Mixin(m.Name); first != m {
+			errors = append(errors, errorAt("Mixin "+m.Name+" is already declared at "+first.Span.Start.String(), m.Span.Start))
+		}
+	}
+	for _, t := range append(append([]*Table{}, p.Mixins...), p.Tables...) {
+		if !partial || p.mixinsKnown(t, map[string]bool{}) {
+			errors = append(errors, p.applyMixins(t, nil)...)
+		}
+	}
+	return errors
+}
+
+// mixinsKnown reports whether every mixin t uses, directly or not, is in the
+// tree. seen holds the mixins already looked at, so that cycles end.
+func (p *ParseTree) mixinsKnown(t *Table, seen map[string]bool) bool {
+	for _, name := range t.Mixins {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		m := p.Mixin(name)
+		if m == nil || !p.mixinsKnown(m, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyMixins copies the contents of t's mixins into it. stack holds the
+// mixins being applied further up, to detect cycles.
+func (p *ParseTree) applyMixins(t *Table, stack []string) []error {
+	for i, name := range stack {
+		if name == t.Name {
+			chain := append(append([]string{}, stack[i:]...), t.Name)
+			return []error{errorAt("Mixin cycle: "+strings.Join(chain, " -> "), t.Span.Start)}
+		}
+	}
+	if t.resolved || t.Mixins == nil {
+		return nil
+	}
+	t.resolved = true
+
+	var errors []error
+	var columns []*Column
+	// taken holds the inherited columns by name, and via the mixin each was
+	// taken from
+	taken := map[string]*Column{}
+	via := map[string]string{}
+	for _, name := range t.Mixins {
+		m := p.Mixin(name)
+		if m == nil {
+			errors = append(errors, errorAt("Unknown mixin "+name+" in table "+t.Name, t.Span.Start))
+			continue
+		}
+		errors = append(errors, p.applyMixins(m, append(stack, t.Name))...)
+		for _, col := range m.Columns {
+			inherited := *col
+			if inherited.From == "" {
+				inherited.From = m.Name
+			}
+			if t.ownColumn(col.Name) {
+				continue
+			}
+			if prev, ok := taken[col.Name]; ok {
+				if prev.From != inherited.From {
+					errors = append(errors, errorAt("Column "+col.Name+" of table "+t.Name+
+						" is defined by both mixins "+via[col.Name]+" and "+name, t.Span.Start))
+				}
+				continue
+			}
+			taken[col.Name], via[col.Name] = &inherited, name
+			columns = append(columns, &inherited)
+		}
+		for _, con := range m.Constraints {
+			inherited := *con
+			if inherited.From == "" {
+				inherited.From = m.Name
+			}
+			if !t.hasConstraint(&inherited) {
+				t.Constraints = append(t.Constraints, &inherited)
+			}
+		}
+		for _, idx := range m.Indexes {
+			inherited := *idx
+			if inherited.From == "" {
+				inherited.From = m.Name
+			}
+			if !t.hasIndex(&inherited) {
+				t.Indexes = append(t.Indexes, &inherited)
+			}
+		}
+	}
+	t.Columns = append(columns, t.Columns...)
+	return append(errors, t.checkColumns()...)
+}
+
+// ownColumn reports whether t itself declares a column with the given name.
+func (t *Table) ownColumn(name string) bool {
+	for _, col := range t.Columns {
+		if col.Name == name && col.From == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasConstraint reports whether t already has con, declared by t itself or
+// taken from another mixin, as happens when two of its mixins use a third.
+// Comments don't count, so t's own copy of a constraint wins.
+func (t *Table) hasConstraint(con *TableConstraint) bool {
+	for _, c := range t.Constraints {
+		if sameName(c.Name, con.Name) && strings.Join(c.Columns, ",") == strings.Join(con.Columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIndex is hasConstraint for indexes.
+func (t *Table) hasIndex(idx *Index) bool {
+	for _, i := range t.Indexes {
+		if i.withoutComment() == idx.withoutComment() {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutComment returns the index as String writes it, less its comment.
+func (i *Index) withoutComment() string {
+	j := *i
+	j.Comment = ""
+	return j.String()
+}
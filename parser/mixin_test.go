@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mixinSchema = `[mixin identified]
+id int
+- primary key
+
+[mixin timestamps]
+created_at time
+updated_at time
+!index by_creation(created_at)
+
+`
+
+var mixinMatchesTests = []struct {
+	name string
+	input string
+	expected Table
+}{
+	{"Single Mixin", "[student : identified]\nname string", Table{Name: "student", Mixins: []string{"identified"}, Columns: []*Column{
+		{Name: "id", Type: "int", From: "identified", Constraints: []*Constraint{{Name: "primary key"}}},
+		{Name: "name", Type: "string"},
+	}}},
+	{"Several Mixins", "[student : identified, timestamps]\nname string", Table{
+		Name: "student",
+		Mixins: []string{"identified", "timestamps"},
+		Columns: []*Column{
+			{Name: "id", Type: "int", From: "identified", Constraints: []*Constraint{{Name: "primary key"}}},
+			{Name: "created_at", Type: "time", From: "timestamps"},
+			{Name: "updated_at", Type: "time", From: "timestamps"},
+			{Name: "name", Type: "string"},
+		},
+		Indexes: []*Index{{Name: "by_creation", Columns: []string{"created_at"}}},
+	}},
+	{"Own Column Overrides", "[student:identified]\nid string\n!unique(id)", Table{
+		Name: "student",
+		Mixins: []string{"identified"},
+		Columns: []*Column{{Name: "id", Type: "string"}},
+		Constraints: []*TableConstraint{{Name: "unique", Columns: []string{"id"}}},
+	}},
+	{"Constraint On Inherited Column", "[student : identified, timestamps]\n!unique(id, created_at)", Table{
+		Name: "student",
+		Mixins: []string{"identified", "timestamps"},
+		Columns: []*Column{
+			{Name: "id", Type: "int", From: "identified", Constraints: []*Constraint{{Name: "primary key"}}},
+			{Name: "created_at", Type: "time", From: "timestamps"},
+			{Name: "updated_at", Type: "time", From: "timestamps"},
+		},
+		Constraints: []*TableConstraint{{Name: "unique", Columns: []string{"id", "created_at"}}},
+		Indexes: []*Index{{Name: "by_creation", Columns: []string{"created_at"}}},
+	}},
+	{"Repeated Constraint And Index", "[mixin coded]\ncode string\n!unique(code)\n\n[student : coded, timestamps]\n!UNIQUE(code) # own\n!index by_creation(created_at) # own", Table{
+		Name: "student",
+		Mixins: []string{"coded", "timestamps"},
+		Columns: []*Column{
+			{Name: "code", Type: "string", From: "coded"},
+			{Name: "created_at", Type: "time", From: "timestamps"},
+			{Name: "updated_at", Type: "time", From: "timestamps"},
+		},
+		Constraints: []*TableConstraint{{Name: "UNIQUE", Columns: []string{"code"}, Comment: "own"}},
+		Indexes: []*Index{{Name: "by_creation", Columns: []string{"created_at"}, Comment: "own"}},
+	}},
+	{"Nested Mixins", "[mixin entity : identified, timestamps]\n\n[mixin audited : entity]\nedited_by string\n\n[doc : audited, timestamps]", Table{
+		Name: "doc",
+		Mixins: []string{"audited", "timestamps"},
+		Columns: []*Column{
+			{Name: "id", Type: "int", From: "identified", Constraints: []*Constraint{{Name: "primary key"}}},
+			{Name: "created_at", Type: "time", From: "timestamps"},
+			{Name: "updated_at", Type: "time", From: "timestamps"},
+			{Name: "edited_by", Type: "string", From: "audited"},
+		},
+		Indexes: []*Index{{Name: "by_creation", Columns: []string{"created_at"}}},
+	}},
+}
+
+func TestMixinMatches(t *testing.T) {
+	for _, test := range mixinMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(mixinSchema + test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			tab := tree.Tables[len(tree.Tables)-1]
+			if !tab.Equals(test.expected) {
+				tt.Fatalf("Incorrect table. Expected:\n%s\ngot:\n%s", &test.expected, tab)
+			}
+			again, errs := Parse(strings.NewReader(tree.String()))
+			if errs != nil || !again.Tables[len(again.Tables)-1].Equals(test.expected) || again.String() != tree.String() {
+				tt.Fatalf("Tree not preserved by String:\n%s", tree)
+			}
+		})
+	}
+}
+
+var mixinRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Unknown Mixin", "[student : named]\nid int", "10:1:Unknown mixin named in table student"},
+	{"Clashing Mixins", "[mixin stamped]\ncreated_at string\n\n[student : timestamps, stamped]", "13:1:Column created_at of table student is defined by both mixins timestamps and stamped"},
+	{"Cycle", "[mixin a : b]\n\n[mixin b : a]\n\n[student : a]", "10:1:Mixin cycle: a -> b -> a"},
+	{"Repeated Mixin", "[mixin timestamps]\nat time", "10:1:Mixin timestamps is already declared at 5:1"},
+	{"Unknown Column In Constraint", "[student : identified]\n!unique(name)", "11:1:Unknown column name in unique of table student"},
+	{"Missing Mixin Name", "[student : ]", "10:1:Invalid table name"},
+}
+
+func TestMixinRejects(t *testing.T) {
+	for _, test := range mixinRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := Parse(strings.NewReader(mixinSchema + test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+func TestMixinsResolvedOnce(t *testing.T) {
+	tree, errs := Parse(strings.NewReader(mixinSchema + "[student : identified, timestamps]"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	before := tree.String()
+	if errs := tree.Resolve(); errs != nil || len(tree.Tables[0].Columns) != 3 || tree.String() != before {
+		t.Fatalf("Resolving again changed the tree: %v\n%s", errs, tree)
+	}
+}
+
+func TestMixinsAcrossIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.orb": "#include = common.orb\n[student : timestamps]\nname string\n-references: school.name",
+		"common.orb": mixinSchema + "[school : identified]\nname string",
+	})
+	tree, errs := ParseFile(filepath.Join(dir, "main.orb"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if student := tree.Table("student"); len(student.Columns) != 3 || student.Column("created_at").From != "timestamps" {
+		t.Fatalf("Mixins not applied across files: %s", student)
+	}
+}
@@ -0,0 +1,337 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// NamedType is a column type declared once and used by name, with a #type
+// line:
+//
+//	#type email = string(320) using VARCHAR(320) - NOT NULL - unique
+//
+// The part after = is written as on a column line, and can be followed by
+// constraints, each starting with a dash. A column whose type is the name
+// is expanded when the tree is resolved: it takes the named type's type,
+// its requested type unless it has its own, and each of its constraints
+// that it doesn't override with one of the same name. Named types can be
+// defined in terms of other named types.
+type NamedType struct {
+	Name string
+	TypeRef *TypeRef
+	Nullable bool
+	RequestedType string
+	DialectTypes []*DialectType
+	Constraints []*Constraint
+	// Comment is the trailing comment on the #type line.
+	Comment string
+	Span Span
+	// base is the named type this one is defined in terms of, if any, and
+	// resolved is set once it has been looked for.
+	base *NamedType
+	resolved bool
+}
+
+func (n *NamedType) String() string {
+	s := "#type " + n.Name + " = " + n.TypeRef.String()
+	if n.Nullable {
+		s += "?"
+	}
+	if requested := requestedTypeString(n.RequestedType, n.DialectTypes); requested != "" {
+		s += " using " + escapeComments(requested)
+	}
+	for _, con := range n.Constraints {
+		// a space after the dash, so that a name such as 0 isn't read
+		// back as a negative number
+		s += " - " + strings.TrimPrefix(con.String(), "-")
+	}
+	return s + commentString(n.Comment)
+}
+
+// Type returns the named type with the given name, or nil if there is none.
+func (p *ParseTree) Type(name string) *NamedType {
+	for _, n := range p.Types {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// isNamedType reports whether a line starting with # declares a named type
+// rather than being a directive or a comment.
+func isNamedType(c *cursor) bool {
+	return len(c.toks) > 4 && c.toks[1].Kind == lexer.Ident && c.toks[1].Text == "type" &&
+		c.toks[2].Kind == lexer.Ident && c.toks[3].Kind == lexer.Equals
+}
+
+// ParseNamedType reads a line of the form
+// `#type name = type [using requested type] [- constraint ...]`.
+func ParseNamedType(input Input) (*NamedType, []error) {
+	input.Scan()
+	c := newCursor(input)
+	if c.illegal() || !c.accept(lexer.Hash) || !c.accept(lexer.Ident) {
+		return nil, []error{NewError("Ill-formed named type", input)}
+	}
+	name := c.peek()
+	if !c.accept(lexer.Ident) {
+		return nil, []error{errorAt("Missing name of type", name.Pos)}
+	}
+	if !c.accept(lexer.Equals) {
+		return nil, []error{errorAt("Missing = after type name", c.peek().Pos)}
+	}
+	n := &NamedType{Name: name.Text, Comment: c.comment, Span: c.lineSpan()}
+	n.Nullable = parseOptional(c)
+	typ := c.peek()
+	t, err := parseType(c)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if t.Name == "" {
+		return nil, []error{errorAt("Missing type after =", typ.Pos)}
+	}
+	n.TypeRef = t
+	if parseOptional(c) {
+		n.Nullable = true
+	}
+
+	end := c.nextSeparator(c.i)
+	if c.accept(lexer.Using) {
+		var err error
+		if n.RequestedType, n.DialectTypes, err = parseRequestedType(c.sub(c.i, end)); err != nil {
+			return nil, []error{err}
+		}
+		c.i = end
+	}
+	if c.i != end {
+		return nil, []error{errorAt("Invalid column type", typ.Pos)}
+	}
+	for !c.done() {
+		c.next()
+		end := c.nextSeparator(c.i)
+		con, err := parseInlineConstraint(c.sub(c.i, end))
+		if err != nil {
+			return nil, []error{err}
+		}
+		n.Constraints = append(n.Constraints, con)
+		c.i = end
+	}
+	return n, nil
+}
+
+// nextSeparator returns the index of the first dash from token i on that
+// starts an inline constraint, or the index of EOF if there is none. Such a
+// dash follows a space, and is followed by a space or a word, so that a
+// negative number isn't taken for one.
+func (c *cursor) nextSeparator(i int) int {
+	for ; c.toks[i].Kind != lexer.EOF; i++ {
+		if c.toks[i].Kind != lexer.Dash || i == 0 || c.joined2(i-1) {
+			continue
+		}
+		next := c.toks[i+1]
+		if !c.joined2(i) || next.Kind == lexer.Ident && !isDigit(next.Text[0]) {
+			return i
+		}
+	}
+	return i
+}
+
+// joined2 reports whether token i+1 directly follows token i.
+func (c *cursor) joined2(i int) bool {
+	return c.toks[i+1].Pos.Offset == c.toks[i].End().Offset
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// sub returns a cursor over tokens [from, to) of c.
+func (c *cursor) sub(from, to int) *cursor {
+	toks := append([]lexer.Token{}, c.toks[from:to]...)
+	toks = append(toks, lexer.Token{Kind: lexer.EOF, Pos: c.toks[to].Pos})
+	return &cursor{line: c.line, start: c.start, toks: toks}
+}
+
+// parseInlineConstraint reads a constraint written on the same line as
+// something else, without its leading dash.
+func parseInlineConstraint(c *cursor) (*Constraint, error) {
+	start := c.peek().Pos
+	span := c.span(0, len(c.toks)-1)
+	dialects, err := c.trailingWhen()
+	if err != nil {
+		return nil, err
+	}
+	con := &Constraint{Name: c.until(lexer.Colon), Span: span, Dialects: dialects}
+	if con.Name == "" {
+		return nil, errorAt("Ill-formed constraint", start)
+	}
+	if con.Name == "alias" {
+		return nil, errorAt("Alias is not a constraint", start)
+	}
+	if !c.accept(lexer.Colon) {
+		return con, nil
+	}
+	literal := c.peek()
+	con.Raw = c.rest()
+	con.Value = con.Raw
+	if con.Raw == "" {
+		return nil, errorAt("Missing constraint value", literal.Pos)
+	}
+	if literal.Kind == lexer.String && con.Raw == literal.Text {
+		value, err := lexer.Unquote(literal.Text)
+		if err != nil {
+			return nil, errorAt("Invalid escape sequence in string literal", literal.Pos)
+		}
+		con.Value = value
+		con.Quoted = true
+	}
+	return con, nil
+}
+
+// resolveType finds the named type n is defined in terms of, if any. stack
+// holds the named types being resolved further up, to detect cycles.
+func (p *ParseTree) resolveType(n *NamedType, stack []string) []error {
+	for i, name := range stack {
+		if name == n.Name {
+			chain := append(append([]string{}, stack[i:]...), n.Name)
+			return []error{errorAt("Named type cycle: "+strings.Join(chain, " -> "), n.Span.Start)}
+		}
+	}
+	if n.resolved {
+		return nil
+	}
+	n.resolved = true
+	base := p.Type(n.TypeRef.Name)
+	if base == nil || n.TypeRef.Elem != nil || n.TypeRef.Args != nil {
+		return nil
+	}
+	errors := p.resolveType(base, append(stack, n.Name))
+	if errors == nil {
+		n.base = base
+	}
+	return errors
+}
+
+// expanded returns n with the named type it is defined in terms of, if
+// any, expanded into it.
+func (n *NamedType) expanded() *NamedType {
+	if n.base == nil {
+		return n
+	}
+	base := n.base.expanded()
+	out := *n
+	out.TypeRef = base.TypeRef
+	out.Nullable = n.Nullable || base.Nullable
+	if out.RequestedType == "" && out.DialectTypes == nil {
+		out.RequestedType, out.DialectTypes = base.RequestedType, base.DialectTypes
+	}
+	out.Constraints = mergeConstraints(base.Constraints, n.Constraints, "")
+	out.base = nil
+	return &out
+}
+
+// mergeConstraints returns the constraints of a named type followed by
+// own, leaving out those that own overrides. The ones taken from the named
+// type are marked as coming from it, unless from is "". A constraint in own
+// that only applies to some dialects overrides the named type's for those
+// dialects alone, which Project sorts out.
+func mergeConstraints(named, own []*Constraint, from string) []*Constraint {
+	var everywhere []*Constraint
+	for _, con := range own {
+		if con.Dialects == nil {
+			everywhere = append(everywhere, con)
+		}
+	}
+	var out []*Constraint
+	for _, con := range named {
+		if !hasConstraintNamed(everywhere, con.Name) {
+			inherited := *con
+			if from != "" && inherited.From == "" {
+				inherited.From = from
+			}
+			out = append(out, &inherited)
+		}
+	}
+	return append(out, own...)
+}
+
+func hasConstraintNamed(cons []*Constraint, name string) bool {
+	for _, con := range cons {
+		if sameName(con.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameName reports whether two constraint names are the same, ignoring case
+// and spacing.
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// expandTypes expands every column, in tables, mixins and views, whose type
+// is a named type.
+func (p *ParseTree) expandTypes() []error {
+	var errors []error
+	for _, n := range p.Types {
+		if first := p.Type(n.Name); first != n {
+			errors = append(errors, errorAt("Type "+n.Name+" is already declared at "+first.Span.Start.String(), n.Span.Start))
+		}
+	}
+	for _, n := range p.Types {
+		errors = append(errors, p.resolveType(n, nil)...)
+	}
+	for _, col := range p.columns() {
+		n := p.Type(col.Type)
+		if n == nil || col.named != nil || col.From != "" || col.TypeRef != nil && col.TypeRef.Elem != nil {
+			continue
+		}
+		if col.TypeArgs != nil {
+			errors = append(errors, errorAt("Named type "+n.Name+" takes no arguments", col.Span.Start))
+			continue
+		}
+		errors = append(errors, col.expand(n)...)
+	}
+	return errors
+}
+
+// expand applies named type n to the column. The named type's nullability
+// is a default too: an optional column drops its NOT NULL and PRIMARY KEY
+// constraints, and one with its own NOT NULL or PRIMARY KEY drops its NULL
+// constraint and optional mark. Conflicts between the column's own
+// constraints have already been reported, so only those the named type
+// brings in are, at the column.
+func (c *Column) expand(n *NamedType) []error {
+	own := &Column{Name: c.Name, Nullable: c.Nullable, Constraints: c.Constraints}
+	reported := own.checkNullable() != nil
+	_, ownNotNull := nullConstraints(c.Constraints)
+	n = n.expanded()
+	c.named = n
+	c.TypeName = n.Name
+	t := *n.TypeRef
+	c.TypeRef = &t
+	c.Type, c.TypeArgs = t.Name, t.Args
+	if c.RequestedType == "" && c.DialectTypes == nil {
+		c.RequestedType, c.DialectTypes = n.RequestedType, n.DialectTypes
+	}
+	var inherited []*Constraint
+	for _, con := range n.Constraints {
+		if c.Nullable && nullKind(con) == "NOT NULL" || ownNotNull != nil && nullKind(con) == "NULL" {
+			continue
+		}
+		inherited = append(inherited, con)
+	}
+	if ownNotNull == nil {
+		c.Nullable = c.Nullable || n.Nullable
+	}
+	c.Constraints = mergeConstraints(inherited, c.Constraints, n.Name)
+	var errors []error
+	for _, err := range c.checkNullable() {
+		if !reported {
+			errors = append(errors, errorAt(err.(*ParseError).msg, c.Span.Start))
+		}
+	}
+	return errors
+}
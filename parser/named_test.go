@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var namedTypeMatchesTests = []struct {
+	name string
+	input string
+	expected string
+}{
+	{"Type Only", "#type id = int", "#type id = int"},
+	{"Requested Type", "#type email = string(320) using VARCHAR(320)", "#type email = string(320) using VARCHAR(320)"},
+	{"Constraints", "#type email = string using VARCHAR(320) - NOT NULL - unique", "#type email = string using VARCHAR(320) - NOT NULL - unique"},
+	{"Constraint Values", "#type amount = decimal(10,2) -default: -1 - check: amount > 0 # money", "#type amount = decimal(10,2) - default: -1 - check: amount > 0 # money"},
+	{"Quoted Value", "#type label = string - default: 'a - b'", "#type label = string - default: 'a - b'"},
+	{"Optional", "#type note = optional string using TEXT", "#type note = string? using TEXT"},
+	{"Collection", "#type tags = []string using TEXT[]", "#type tags = []string using TEXT[]"},
+	{"Numeric Constraint Name", "#type x = int - 0", "#type x = int - 0"},
+	{"Dialect Types", "#type doc = string using JSONB when postgres, TEXT - NOT NULL [when pg]", "#type doc = string using JSONB when postgres, TEXT - NOT NULL [when postgres]"},
+}
+
+func TestParseNamedTypeMatches(t *testing.T) {
+	for _, test := range namedTypeMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			n, errs := ParseNamedType(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if n.String() != test.expected {
+				tt.Fatalf("Incorrect named type. Expected %s; got %s", test.expected, n)
+			}
+			again, errs := ParseNamedType(DummyScanner(n.String()))
+			if errs != nil || again.String() != test.expected {
+				tt.Fatalf("Named type not preserved by String: %s", n)
+			}
+		})
+	}
+}
+
+var namedTypeRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Missing Type", "#type email =", "1:14:Missing type after ="},
+	{"Missing Requested Type", "#type email = string using - NOT NULL", "1:28:Missing type after using"},
+	{"Text After Type", "#type email = string text", "1:15:Invalid column type"},
+	{"Empty Constraint", "#type email = string - ", "1:24:Ill-formed constraint"},
+	{"Alias Constraint", "#type email = string - alias: e", "1:24:Alias is not a constraint"},
+}
+
+func TestParseNamedTypeRejects(t *testing.T) {
+	for _, test := range namedTypeRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := ParseNamedType(DummyScanner(test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+const namedTypeSchema = `#type email = string(320) using VARCHAR(320) - NOT NULL - unique
+#type work_email = email - check: email LIKE '%@example.com'
+#type memo = string - NULL
+`
+
+var namedTypeExpandsTests = []struct {
+	name string
+	input string
+	expected Column
+}{
+	{"Expanded", "address email", Column{
+		Name: "address", Type: "string", TypeArgs: []int{320}, RequestedType: "VARCHAR(320)", TypeName: "email",
+		Constraints: []*Constraint{{Name: "NOT NULL", From: "email"}, {Name: "unique", From: "email"}},
+	}},
+	{"Column Overrides", "address email using TEXT\n-Unique # per tenant\n-default: ''", Column{
+		Name: "address", Type: "string", TypeArgs: []int{320}, RequestedType: "TEXT", TypeName: "email",
+		Constraints: []*Constraint{
+			{Name: "NOT NULL", From: "email"},
+			{Name: "Unique", Comment: "per tenant"},
+			{Name: "default", Value: "", Raw: "''", Quoted: true},
+		},
+	}},
+	{"Named In Terms Of Another", "address work_email", Column{
+		Name: "address", Type: "string", TypeArgs: []int{320}, RequestedType: "VARCHAR(320)", TypeName: "work_email",
+		Constraints: []*Constraint{
+			{Name: "NOT NULL", From: "work_email"},
+			{Name: "unique", From: "work_email"},
+			{Name: "check", Value: "email LIKE '%@example.com'", From: "work_email"},
+		},
+	}},
+	{"Optional Column", "address email?", Column{
+		Name: "address", Type: "string", TypeArgs: []int{320}, RequestedType: "VARCHAR(320)", TypeName: "email", Nullable: true,
+		Constraints: []*Constraint{{Name: "unique", From: "email"}},
+	}},
+	{"Not Null Column", "note memo\n-NOT NULL", Column{
+		Name: "note", Type: "string", TypeName: "memo",
+		Constraints: []*Constraint{{Name: "NOT NULL"}},
+	}},
+}
+
+func TestNamedTypeExpands(t *testing.T) {
+	for _, test := range namedTypeExpandsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			input := namedTypeSchema + "[users]\n" + test.input
+			tree, errs := Parse(strings.NewReader(input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			col := tree.Tables[0].Columns[0]
+			if !col.Equals(test.expected) {
+				tt.Fatalf("Incorrect column. Expected:\n%s\ngot:\n%s", &test.expected, col)
+			}
+			if !strings.HasSuffix(tree.String(), "[users]\n"+test.input+"\n") {
+				tt.Fatalf("Column not preserved by String:\n%s", tree)
+			}
+		})
+	}
+}
+
+var namedTypeConflictsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"With Arguments", "[users]\naddress email(12)", "5:1:Named type email takes no arguments"},
+	{"Conflict In Named Type", "#type odd = int? - primary key\n[users]\nid odd", "6:1:Column id is optional but has a primary key constraint"},
+	{"Repeated", "#type email = string\n", "4:1:Type email is already declared at 1:1"},
+	{"Cycle", "#type a = b\n#type b = a\n", "4:1:Named type cycle: a -> b -> a"},
+}
+
+func TestNamedTypeConflicts(t *testing.T) {
+	for _, test := range namedTypeConflictsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := Parse(strings.NewReader(namedTypeSchema + test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+func TestNamedTypeIsNotDirective(t *testing.T) {
+	tree, errs := Parse(strings.NewReader("# type of user below\n#type = strict\n#type email = string"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(tree.Directives) != 1 || tree.Directive("type").Value() != "strict" || len(tree.Types) != 1 {
+		t.Fatalf("Incorrect tree:\n%s", tree)
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"strings"
+)
+
+// A table name can be qualified by a namespace, such as a Postgres schema:
+//
+//	#schema = billing
+//
+//	[auth.user]
+//	id int
+//
+//	[invoice]
+//	id int
+//
+// The `#schema` directive sets the namespace of every table in the same file
+// that doesn't give one, so invoice above is billing.invoice.
+
+// splitTableName splits a header name into its namespace and name. It
+// reports false if the name has more than one dot or an empty part.
+func splitTableName(qualified string) (string, string, bool) {
+	if !validKey(qualified) || strings.Count(qualified, ".") > 1 {
+		return "", "", false
+	}
+	if i := strings.LastIndex(qualified, "."); i >= 0 {
+		return qualified[:i], qualified[i+1:], true
+	}
+	return "", qualified, true
+}
+
+// QualifiedName returns the table's name with its namespace, if it has one,
+// as in auth.user.
+func (t *Table) QualifiedName() string {
+	if t.Namespace == "" {
+		return t.Name
+	}
+	return t.Namespace + "." + t.Name
+}
+
+// applySchema gives every table and view without a namespace the one set by the
+// `#schema` directive, if any. It is applied to each file on its own, so
+// that the directive only covers the tables declared alongside it.
+func (p *ParseTree) applySchema() []error {
+	schema := p.Directive("schema")
+	if schema == nil {
+		return nil
+	}
+	if len(schema.Items) != 1 || schema.Version() != "" || !validKey(schema.Value()) || strings.Contains(schema.Value(), ".") {
+		return []error{errorAt("Schema must be a single name", schema.Span.Start)}
+	}
+	for _, t := range p.Tables {
+		if t.Namespace == "" {
+			t.Namespace = schema.Value()
+			t.defaultNamespace = true
+		}
+	}
+	for _, v := range p.Views {
+		if v.Namespace == "" {
+			v.Namespace = schema.Value()
+			v.defaultNamespace = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var namespaceMatchesTests = []struct {
+	name string
+	input string
+	expected []string
+}{
+	{"Unqualified", "[user]", []string{"user"}},
+	{"Qualified", "[auth.user]\n\n[billing.invoice]", []string{"auth.user", "billing.invoice"}},
+	{"Default Schema", "#schema = billing\n[invoice]\n\n[auth.user]", []string{"billing.invoice", "auth.user"}},
+	{"Default Schema After Tables", "[invoice]\n\n#schema = billing", []string{"billing.invoice"}},
+	{"With Mixins", "[mixin stamped]\nat time\n\n[auth.user : stamped]", []string{"auth.user"}},
+}
+
+func TestNamespaceMatches(t *testing.T) {
+	for _, test := range namespaceMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			var names []string
+			for _, tab := range tree.Tables {
+				names = append(names, tab.QualifiedName())
+			}
+			if strings.Join(names, " ") != strings.Join(test.expected, " ") {
+				tt.Fatalf("Incorrect tables. Expected %v; got %v", test.expected, names)
+			}
+			again, errs := Parse(strings.NewReader(tree.String()))
+			if errs != nil || again.String() != tree.String() {
+				tt.Fatalf("Tree not preserved by String:\n%s", tree)
+			}
+		})
+	}
+}
+
+var namespaceRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Too Many Parts", "[db.auth.user]", "1:1:Invalid table name"},
+	{"Empty Namespace", "[.user]", "1:1:Invalid table name"},
+	{"Empty Name", "[auth.]", "1:1:Invalid table name"},
+	{"Dotted Schema", "#schema = db.auth\n[user]", "1:1:Schema must be a single name"},
+	{"Schema List", "#schema = auth, billing\n[user]", "1:1:Schema must be a single name"},
+	{"Ambiguous Reference", "[auth.user]\nid int\n\n[billing.user]\nuid int\n\n[invoice]\nowner int\n-references: user.uid", "9:1:Reference to table user is ambiguous between auth.user and billing.user"},
+	{"Repeated Table", "#schema = auth\n[user]\nid int\n\n[auth.user]\nid int", "5:1:Table auth.user is already declared at 2:1"},
+}
+
+func TestNamespaceRejects(t *testing.T) {
+	for _, test := range namespaceRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := Parse(strings.NewReader(test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+var namespaceLookupTests = []struct {
+	name string
+	lookup string
+	expected string
+}{
+	{"Qualified", "auth.user", "auth.user"},
+	{"Other Namespace", "billing.user", "billing.user"},
+	{"Unqualified", "invoice", "billing.invoice"},
+	{"Ambiguous", "user", ""},
+	{"Unknown Namespace", "crm.user", ""},
+}
+
+func TestNamespaceLookup(t *testing.T) {
+	tree, errs := Parse(strings.NewReader("[auth.user]\nid int\n\n[billing.user]\nid int\nowner int\n-references: auth.user.id\n\n[billing.invoice]\nid int"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	for _, test := range namespaceLookupTests {
+		t.Run(test.name, func(tt *testing.T) {
+			found := ""
+			if tab := tree.Table(test.lookup); tab != nil {
+				found = tab.QualifiedName()
+			}
+			if found != test.expected {
+				tt.Fatalf("Incorrect table for %s. Expected %q; got %q", test.lookup, test.expected, found)
+			}
+		})
+	}
+	if ref := tree.Tables[1].Column("owner").Reference; ref.Table != "auth.user" || ref.Column != "id" {
+		t.Fatalf("Incorrect qualified reference: %s", ref)
+	}
+}
+
+func TestSchemaPerFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.orb": "#include = auth.orb\n#schema = billing\n[invoice]\nowner int\n-references: auth.user.id",
+		"auth.orb": "#schema = auth\n[user]\nid int",
+	})
+	tree, errs := ParseFile(filepath.Join(dir, "main.orb"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if names := tree.Tables[0].QualifiedName() + " " + tree.Tables[1].QualifiedName(); names != "auth.user billing.invoice" {
+		t.Fatalf("Incorrect namespaces: %s", names)
+	}
+}
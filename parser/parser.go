@@ -1,233 +1,818 @@
 package parser
 
 import (
-	// "fmt"
-	"strings"
-	"regexp"
+	"bufio"
+	"errors"
 	"io"
-	"strconv"
+	"strings"
+
+	"orb/lexer"
 )
 
+// Pos and Span locate nodes in the source. Every node produced by Parse
+// records the span of text it was parsed from.
+type Pos = lexer.Pos
+type Span = lexer.Span
+
 type ParseError struct {
 	msg string
-	line int
+	pos Pos
+	// cause is the underlying error for problems that didn't come from the
+	// text itself, such as a failed read.
+	cause error
+}
+
+func NewError(msg string, s Input) *ParseError {
+	return &ParseError{msg: msg, pos: s.Pos()}
 }
 
-func NewError(msg string, s *Scanner) *ParseError {
-	return &ParseError{msg, s.Line()}
+func errorAt(msg string, pos Pos) *ParseError {
+	return &ParseError{msg: msg, pos: pos}
 }
 
 func (p *ParseError) Error() string {
-	return strconv.Itoa(p.line) + ":" + p.msg
+	return p.pos.String() + ":" + p.msg
+}
+
+func (p *ParseError) Pos() Pos {
+	return p.pos
+}
+
+func (p *ParseError) Unwrap() error {
+	return p.cause
+}
+
+// Warning flags a line that parsed, but probably doesn't mean what its
+// author intended. Warnings are kept apart from errors, since the tree is
+// complete either way.
+type Warning struct {
+	Msg string
+	// Fix is a suggested replacement for the line, or "" if there isn't an
+	// obvious one.
+	Fix string
+	Span Span
+}
+
+func (w *Warning) String() string {
+	s := w.Span.Start.String() + ":" + w.Msg
+	if w.Fix != "" {
+		s += ", did you mean " + w.Fix + "?"
+	}
+	return s
+}
+
+// BadLine is an error node. It stands in for a line that couldn't be parsed,
+// so that the rest of the tree can still be used.
+type BadLine struct {
+	Text string
+	Span Span
+	Err error
+}
+
+func newBadLine(input Input, err error) *BadLine {
+	return &BadLine{Text: input.Text(), Span: newCursor(input).lineSpan(), Err: err}
 }
 
 type ParseTree struct {
-	Directives map[string]string
+	// Directives holds every directive in source order. A directive set more
+	// than once appears once per definition; use Directive to look one up.
+	Directives []*Directive
+	// Includes lists the #include directives, in order. Parse only records
+	// them; ParseFile reads the files and merges them into the tree.
+	Includes []*Include
 	Tables []*Table
+	// Types holds the named types declared with #type lines.
+	Types []*NamedType
+	// Mixins holds the abstract tables that other tables take columns from.
+	Mixins []*Table
+	Enums []*Enum
+	Views []*View
+	// Bad holds lines outside any table, enum or view that couldn't be parsed,
+	// including invalid headers.
+	Bad []*BadLine
+	// Warnings holds lines that parsed but look like mistakes, such as a
+	// comment that was probably meant to be a directive.
+	Warnings []*Warning
 }
 
 func NewParseTree() *ParseTree {
-	return &ParseTree{make(map[string]string), nil}
+	return &ParseTree{}
 }
 
 func (p *ParseTree) String() string {
 	var out string
-	for d, v := range p.Directives {
-		out += "#" + d + "=" + v + "\n"
+	for _, d := range p.Directives {
+		out += d.String() + "\n"
+	}
+	for _, n := range p.Types {
+		out += n.String() + "\n"
+	}
+	for _, e := range p.Enums {
+		out += "\n" + e.String()
+	}
+	for _, m := range p.Mixins {
+		out += "\n" + m.String()
 	}
 	for _, t := range p.Tables {
 		out += "\n" + t.String()
 	}
+	for _, v := range p.Views {
+		out += "\n" + v.String()
+	}
 	return out
 }
 
 type Table struct {
+	// Namespace is the part of a dotted table name before the dot, such as
+	// a Postgres schema, or the one set by #schema. It is "" if neither
+	// gives one.
+	Namespace string
 	Name string
+	// Abstract is set for a mixin, declared as `[mixin name]`.
+	Abstract bool
+	// Mixins lists the mixins the table uses, from its header.
+	Mixins []string
+	// Doc is the text of the comment lines directly above the table header.
+	Doc string
+	// Comment is the trailing comment on the header line.
+	Comment string
 	Columns []*Column
+	// Constraints holds the table-level constraints, the `!` lines.
+	Constraints []*TableConstraint
+	// Indexes holds the indexes declared on the table, also `!` lines.
+	Indexes []*Index
+	Span Span
+	// Bad holds column, table constraint and index lines that couldn't be parsed.
+	Bad []*BadLine
+	// resolved is set once the mixins have been applied.
+	resolved bool
+	// defaultNamespace is set if Namespace came from #schema.
+	defaultNamespace bool
 }
 
 func (t *Table) String() string {
-	s := "[" + t.Name + "]"
+	s := docString(t.Doc) + t.headerString() + commentString(t.Comment)
 	for _, c := range t.Columns {
-		s += "\n" + c.String()
+		if c.From == "" {
+			s += "\n" + c.String()
+		}
+	}
+	for _, con := range t.Constraints {
+		if con.From == "" {
+			s += "\n" + con.String()
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.From == "" {
+			s += "\n" + idx.String()
+		}
 	}
 	return s + "\n"
 }
 
 type Column struct {
 	Name string
+	// Doc is the text of the comment lines directly above the column.
+	Doc string
+	// Comment is the trailing comment on the column line.
+	Comment string
+	// Type is the name of the column's type, list or set for a collection.
 	Type string
+	// TypeArgs holds the arguments written after the type, such as the
+	// length in string(255) or the precision and scale in decimal(10,2).
+	// It is nil if the type has none.
+	TypeArgs []int
+	// TypeRef is the whole type, including the element types of
+	// collections. Type and TypeArgs repeat its outermost part.
+	TypeRef *TypeRef
+	// Nullable is set if the type is marked optional, as in `string?` or
+	// `optional string`, or the column has a NULL constraint. Otherwise the
+	// column can't hold NULL, whether or not it has a NOT NULL constraint to
+	// say so. SQL columns are nullable by default, so NotNullImplied reports
+	// when a generator has to add NOT NULL itself.
+	Nullable bool
+	// RequestedType is the SQL type given after `using`. DialectTypes holds
+	// the alternatives qualified with `when`, if any, and RequestedType is
+	// then the one for every other dialect, or "".
 	RequestedType string
+	DialectTypes []*DialectType
 	Alias string
+	AliasComment string
+	AliasSpan Span
 	Constraints []*Constraint
+	// Reference is the foreign key the column holds, or nil.
+	Reference *Reference
+	// referenceAt is the number of the column's own constraints written
+	// before the references line, so that String keeps it in place.
+	referenceAt int
+	Span Span
+	// Bad holds alias and constraint lines that couldn't be parsed.
+	Bad []*BadLine
+	// From is the mixin the column was copied from, or "" if the table
+	// declares it itself.
+	From string
+	// TypeName is the named type the column's type was expanded from, or ""
+	// if it wasn't.
+	TypeName string
+	// named is the named type itself, so that String can leave out what
+	// the column took from it.
+	named *NamedType
 }
 
 func (c *Column) String() string {
-	s := c.Name + " " + c.Type
-	if c.RequestedType != "" {
-		s += " using " + c.RequestedType
+	typ := typeString(c.Type, c.TypeArgs)
+	if c.TypeRef != nil {
+		typ = c.TypeRef.String()
+	}
+	named := &NamedType{}
+	if c.named != nil {
+		typ, named = c.named.Name, c.named
+	}
+	s := docString(c.Doc) + c.Name + " " + typ
+	if c.Nullable && !named.Nullable && !c.hasNull() {
+		s += "?"
+	}
+	if requested := requestedTypeString(c.RequestedType, c.DialectTypes); requested != "" && requested != requestedTypeString(named.RequestedType, named.DialectTypes) {
+		s += " using " + escapeComments(requested)
 	}
+	s += commentString(c.Comment)
 	if c.Alias != "" {
-		s += "\n-alias: " + c.Alias
+		s += "\n-alias: " + c.Alias + commentString(c.AliasComment)
 	}
+	own := 0
 	for _, con := range c.Constraints {
+		if con.From != "" {
+			continue
+		}
+		if own == c.referenceAt && c.Reference != nil {
+			s += "\n" + c.Reference.String()
+		}
 		s += "\n" + con.String()
+		own++
+	}
+	if own <= c.referenceAt && c.Reference != nil {
+		s += "\n" + c.Reference.String()
 	}
 	return s
 }
 
 type Constraint struct {
 	Name string
+	// Value is the constraint's value. If it was written as a single string
+	// literal, Value holds the unescaped string and Quoted is set.
 	Value string
+	// Raw is the value exactly as written, quotes and escapes included.
+	Raw string
+	Quoted bool
+	// Lines holds the value as written, one entry per line, when it was
+	// continued over more than one line. Value is then the folded form.
+	Lines []string
+	// Comment is the trailing comment on the constraint line.
+	Comment string
+	Span Span
+	// From is the named type the constraint was taken from, or "" if it
+	// was written under the column.
+	From string
+	// Dialects holds the dialects named by a `[when ...]` qualifier at the
+	// end of the constraint, or nil if it applies to every dialect.
+	Dialects []Dialect
 }
 
 func (c *Constraint) String() string {
-	out := "-" + c.Name
-	if c.Value != "" {
-		out += ": " + c.Value
+	out := "-" + escapeComments(c.Name)
+	if len(c.Lines) > 1 {
+		out += ":"
+		for i, line := range c.Lines {
+			if i > 0 {
+				out += "\n  |"
+			}
+			if line != "" {
+				out += " " + escapeComments(line)
+			}
+		}
+	} else if c.Raw != "" {
+		out += ": " + escapeComments(c.Raw)
+	} else if c.Quoted {
+		out += ": " + lexer.Quote(c.Value, '\'')
+	} else if c.Value != "" {
+		out += ": " + escapeComments(c.Value)
 	}
-	return out
+	if c.Dialects != nil {
+		out += " [" + strings.TrimPrefix(whenString(c.Dialects), " ") + "]"
+	}
+	return out + commentString(c.Comment)
 }
 
+// commentString formats a trailing comment, for String methods.
+func commentString(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return " # " + comment
+}
 
 func Parse(stream io.Reader) (*ParseTree, []error) {
+	return ParseNamed("", stream)
+}
+
+// ParseNamed is Parse for input read from the named file. The name is only
+// used to label positions.
+func ParseNamed(filename string, stream io.Reader) (*ParseTree, []error) {
+	return ParseWithOptions(stream, Options{Filename: filename})
+}
+
+// Options controls how ParseWithOptions reads its input. The zero value
+// gives the same behaviour as Parse.
+type Options struct {
+	// Filename labels positions.
+	Filename string
+	// MaxLineSize is the longest line accepted, in bytes including the line
+	// terminator. Zero means DefaultMaxLineSize.
+	MaxLineSize int
+}
+
+func ParseWithOptions(stream io.Reader, opts Options) (*ParseTree, []error) {
+	return ParseInput(newInput(stream, opts))
+}
+
+func newInput(stream io.Reader, opts Options) *StreamScanner {
+	if opts.MaxLineSize <= 0 {
+		opts.MaxLineSize = DefaultMaxLineSize
+	}
+	input := NewStreamScannerSize(stream, opts.MaxLineSize)
+	input.file = opts.Filename
+	return input
+}
+
+// ParseInput parses lines from any Input, such as a Scanner that has already
+// buffered the whole file, and resolves the tree. Included files aren't
+// read, so if there are any, each include gets a warning, and the tree is
+// only resolved as far as it can be without them: mixins and reference
+// targets that may be declared in those files aren't reported as unknown.
+func ParseInput(input Input) (*ParseTree, []error) {
+	tree, errors := parseInput(input)
+	if len(tree.Includes) == 0 {
+		return tree, append(errors, tree.Resolve()...)
+	}
+	for _, include := range tree.Includes {
+		tree.Warnings = append(tree.Warnings, &Warning{Msg: "Included file " + include.Path + " is only read by ParseFile", Span: include.Span})
+	}
+	return tree, append(errors, tree.resolve(true)...)
+}
+
+// parseInput is ParseInput without resolving references.
+func parseInput(input Input) (*ParseTree, []error) {
 	tree := NewParseTree()
-	input := NewScanner(stream)
 	if input.EOF() {
 		return tree, nil
 	}
 
 	var errors []error
+	// doc collects the comment lines since the last line of anything else
+	var doc []string
 
 	for input.Scan() {
-		if input.Text() == "" {
+		switch newCursor(input).peek().Kind {
+		case lexer.EOF:
+			doc = nil
 			continue
-		} else if strings.HasPrefix(input.Text(), "#") {
+		case lexer.Hash:
+			named := isNamedType(newCursor(input))
+			bad := newBadLine(input, nil)
 			input.Backtrack()
-			if kind, value, valid := ParseDirective(input); valid {
-				tree.Directives[kind] = value
+			if named {
+				n, errs := ParseNamedType(input)
+				errors = append(errors, errs...)
+				if n != nil {
+					tree.Types = append(tree.Types, n)
+				} else {
+					bad.Err = errs[0]
+					tree.Bad = append(tree.Bad, bad)
+				}
+				doc = nil
+			} else if d, valid := ParseDirective(input); valid && d.Name == "include" {
+				for _, item := range d.Items {
+					path := item.Value
+					if item.Version != "" {
+						path += "@" + item.Version
+					}
+					tree.Includes = append(tree.Includes, &Include{path, d.Span})
+				}
+				doc = nil
+			} else if valid {
+				if prev := tree.Directive(d.Name); prev != nil && !prev.sameValue(d) {
+					errors = append(errors, errorAt("Directive "+d.Name+" redefined with a different value, it was "+
+						prev.Raw+" at "+prev.Span.Start.String(), d.Span.Start))
+				}
+				tree.Directives = append(tree.Directives, d)
+				doc = nil
+			} else {
+				// it's a comment, but it may have been meant as a directive
+				if w := checkDirective(input); w != nil {
+					tree.Warnings = append(tree.Warnings, w)
+				}
+				doc = append(doc, commentText(input.Text()))
 			}
-			// else skip it because it's a comment
-			// possibly account for incorrect directives in the future
-		} else if strings.HasPrefix(input.Text(), "[") {
+		case lexer.LBracket:
+			bad := newBadLine(input, nil)
+			enum, view := isEnumHeader(newCursor(input)), isViewHeader(newCursor(input))
 			input.Backtrack()
-			table, errs := ParseTable(input)
-			if errs != nil {
-				errors = append(errors, errs...)
+			var errs []error
+			parsed := false
+			if enum {
+				var e *Enum
+				if e, errs = ParseEnum(input); e != nil {
+					e.Doc = strings.Join(doc, "\n")
+					tree.Enums = append(tree.Enums, e)
+					parsed = true
+				}
+			} else if view {
+				var v *View
+				if v, errs = ParseView(input); v != nil {
+					v.Doc = strings.Join(doc, "\n")
+					tree.Views = append(tree.Views, v)
+					parsed = true
+				}
 			} else {
-				tree.Tables = append(tree.Tables, table)
+				var table *Table
+				if table, errs = ParseTable(input); table != nil && table.Abstract {
+					table.Doc = strings.Join(doc, "\n")
+					tree.Mixins = append(tree.Mixins, table)
+					parsed = true
+				} else if table != nil {
+					table.Doc = strings.Join(doc, "\n")
+					tree.Tables = append(tree.Tables, table)
+					parsed = true
+				}
 			}
-		} else {
-			errors = append(errors, NewError("Invalid token outside table definition", input))
+			errors = append(errors, errs...)
+			if !parsed {
+				bad.Err = errs[0]
+				tree.Bad = append(tree.Bad, bad)
+			}
+			doc = nil
+		default:
+			err := NewError("Invalid token outside table definition", input)
+			errors = append(errors, err)
+			tree.Bad = append(tree.Bad, newBadLine(input, err))
+			doc = nil
 		}
 	}
 
+	errors = append(errors, tree.applySchema()...)
+	if err := input.Err(); err != nil {
+		errors = append(errors, readError(input, err))
+	}
 	return tree, errors
 }
-var directiveFormat = regexp.MustCompile(`^#\s*([-A-Za-z_]+)\s*=\s*([A-Za-z0-9_]+)\s*$`)
 
-func ParseDirective(input *Scanner) (string, string, bool) {
-	input.Scan()
-	matches := directiveFormat.FindStringSubmatch(input.Text())
-	if matches != nil {
-		return matches[1], matches[2], true
+// readError reports an error that stopped the input early. Whatever was
+// parsed before it is kept, but the tree is incomplete.
+func readError(input Input, err error) *ParseError {
+	msg := "Read failed, input is incomplete: " + err.Error()
+	if errors.Is(err, bufio.ErrTooLong) {
+		msg = "Line exceeds the maximum line size, input is incomplete"
 	}
-	// Isn't a compiler directive
-	return "", "", false
+	return &ParseError{msg: msg, pos: input.Pos(), cause: err}
 }
 
-var tableName = regexp.MustCompile(`^\[([-A-Za-z0-9_]+)\]\s*$`)
-
-func ParseTable(input *Scanner) (*Table, []error) {
+// ParseTable reads a table header and its columns, up to a blank line or the
+// next table header. Columns that fail to parse are recorded in Table.Bad and
+// the rest of the table is kept. If the header itself is invalid, the whole
+// block is skipped and no table is returned.
+func ParseTable(input Input) (*Table, []error) {
 	input.Scan()
-	matches := tableName.FindStringSubmatch(input.Text())
-	if matches == nil {
-		return nil, []error{NewError("Invalid table name", input)}
+	c := newCursor(input)
+	table := &Table{Comment: c.comment, Span: c.lineSpan()}
+	if !parseHeader(c, table) {
+		err := NewError("Invalid table name", input)
+		skipBlock(input)
+		return nil, []error{err}
 	}
 
-	table := &Table{Name: matches[1]}
+	errors := parseTableBody(input, table)
+	if table.Mixins != nil {
+		// the columns named may come from the mixins, so wait until they
+		// have been applied
+		return table, errors
+	}
+	return table, append(errors, table.checkColumns()...)
+}
+
+// parseTableBody reads the lines of a table after its header.
+func parseTableBody(input Input, table *Table) []error {
 	var errors []error
+	var doc []string
 	for input.Scan() {
-		if input.Text() == "" {
-			return table, errors
+		switch newCursor(input).peek().Kind {
+		case lexer.EOF:
+			return errors
+		case lexer.Hash:
+			// comments are allowed between columns, and document the next one
+			doc = append(doc, commentText(input.Text()))
+			continue
+		case lexer.LBracket:
+			// the next table starts without a blank line in between
+			input.Backtrack()
+			return errors
+		case lexer.Bang:
+			bad := newBadLine(input, nil)
+			index := isIndex(newCursor(input))
+			input.Backtrack()
+			var errs []error
+			if index {
+				var idx *Index
+				if idx, errs = ParseIndex(input); idx != nil {
+					table.Indexes = append(table.Indexes, idx)
+					table.Span.End = idx.Span.End
+				}
+			} else {
+				var con *TableConstraint
+				if con, errs = ParseTableConstraint(input); con != nil {
+					table.Constraints = append(table.Constraints, con)
+					table.Span.End = con.Span.End
+				}
+			}
+			errors = append(errors, errs...)
+			if errs != nil {
+				bad.Err = errs[0]
+				table.Bad = append(table.Bad, bad)
+			}
+			doc = nil
+			continue
 		}
+		bad := newBadLine(input, nil)
 		input.Backtrack()
 		col, errs := ParseColumn(input)
-		if errs != nil {
-			errors = append(errors, errs...)
-		} else if col != nil {
+		errors = append(errors, errs...)
+		if col != nil {
+			col.Doc = strings.Join(doc, "\n")
 			table.Columns = append(table.Columns, col)
+			table.Span.End = col.Span.End
 		} else {
-			input.Backtrack()
-			break
+			bad.Err = errs[0]
+			table.Bad = append(table.Bad, bad)
 		}
+		doc = nil
 	}
-	return table, errors
+	return errors
 }
 
-// DB types can have spaces, but not internal types
-var columnFormat = regexp.MustCompile(`^([^-\s]+?)\s+([^\s]+?)(?:\s+using\s+([-A-Za-z0-9_]+.*?))?\s*$`)
-var subIndicator = regexp.MustCompile(`^\s*-`)
+// commentText returns the text of a comment line, without the # and the
+// space that usually follows it.
+func commentText(line string) string {
+	text := strings.TrimPrefix(strings.TrimSpace(line), "#")
+	return strings.TrimPrefix(text, " ")
+}
 
-func ParseColumn(input *Scanner) (*Column, []error) {
+// docString formats doc as comment lines, for String methods.
+func docString(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	return "# " + strings.ReplaceAll(doc, "\n", "\n# ") + "\n"
+}
+
+// skipBlock advances past the lines of a table whose header was invalid, so
+// that they aren't reported again one by one. It stops before the next
+// table header, or after a blank line.
+func skipBlock(input Input) {
+	for input.Scan() {
+		switch newCursor(input).peek().Kind {
+		case lexer.EOF:
+			return
+		case lexer.LBracket:
+			input.Backtrack()
+			return
+		}
+	}
+}
+
+// A column line is `name type [using requested type]`. DB types can have
+// spaces, but not internal types. The internal type can take whole-number
+// arguments, as in `price decimal(10,2)`, be a collection, as in
+// `tags []string`, and be marked nullable, as in `nickname string?` or
+// `nickname optional string`.
+//
+// If the column line itself is invalid, its alias and constraint lines are
+// skipped and no column is returned. Invalid alias and constraint lines are
+// recorded in Column.Bad and the rest of the column is kept.
+func ParseColumn(input Input) (*Column, []error) {
 	input.Scan()
 	column := &Column{}
 	var errors []error
-	matches := columnFormat.FindStringSubmatch(input.Text())
-	if matches != nil {
-		column.Name = matches[1]
-		column.Type = matches[2]
-		column.RequestedType = matches[3]
-		for input.Scan() {
-			if strings.Contains(input.Text(), "alias") {
-				input.Backtrack()
-				alias, errs := ParseAlias(input)
-				if errs != nil {
-					errors = append(errors, errs...)
-				}
+
+	c := newCursor(input)
+	column.Span = c.lineSpan()
+	column.Comment = c.comment
+	name := c.peek()
+	typ := lexer.Token{}
+	var typeErr error
+	if !c.illegal() && c.accept(lexer.Ident) {
+		column.Name = name.Text
+		column.Nullable = parseOptional(c)
+		typ = c.peek()
+		column.TypeRef, typeErr = parseType(c)
+		if typeErr == nil {
+			column.Type, column.TypeArgs = column.TypeRef.Name, column.TypeRef.Args
+		}
+		if typeErr == nil && column.Type != "" && parseOptional(c) {
+			column.Nullable = true
+		}
+	}
+	if name.Kind == lexer.Pipe {
+		errors = append(errors, NewError("Continuation line must follow a constraint", input))
+	} else if column.Name == "" {
+		errors = append(errors, NewError("Invalid column definition", input))
+	} else if typeErr != nil {
+		errors = append(errors, typeErr)
+	} else if c.accept(lexer.Using) {
+		var err error
+		if column.RequestedType, column.DialectTypes, err = parseRequestedType(c); err != nil {
+			errors = append(errors, err)
+		}
+	} else if column.Type == "" || !c.done() {
+		errors = append(errors, errorAt("Invalid column type", typ.Pos))
+	}
+	if errors != nil {
+		skipSubLines(input)
+		return nil, errors
+	}
+
+	for input.Scan() {
+		c := newCursor(input)
+		if !c.accept(lexer.Dash) {
+			input.Backtrack()
+			break
+		}
+		bad := newBadLine(input, nil)
+		input.Backtrack()
+		if c.peek().Kind == lexer.Ident && c.peek().Text == "alias" {
+			alias, errs := ParseAlias(input)
+			if errs != nil {
+				errors = append(errors, errs...)
+				bad.Err = errs[0]
+				column.Bad = append(column.Bad, bad)
+			} else {
 				column.Alias = alias
-			} else if subIndicator.MatchString(input.Text()) {
-				input.Backtrack()
-				constraint, errs := ParseConstraint(input)
-				if errs != nil {
-					errors = append(errors, errs...)
-				} else if constraint != nil {
-					column.Constraints = append(column.Constraints, constraint)
+				column.AliasComment = c.comment
+				column.AliasSpan = c.lineSpan()
+			}
+		} else {
+			constraint, errs := ParseConstraint(input)
+			if errs == nil && column.isReference(constraint) {
+				if err := column.addReference(constraint); err != nil {
+					errs = []error{err}
+				} else if referenceLine(constraint) == "references" {
+					column.referenceAt = len(column.Constraints)
 				}
-			} else {
-				input.Backtrack()
-				break
+			} else if errs == nil {
+				column.Constraints = append(column.Constraints, constraint)
+			}
+			if errs != nil {
+				errors = append(errors, errs...)
+				bad.Err = errs[0]
+				column.Bad = append(column.Bad, bad)
 			}
 		}
-	} else {
-		errors = append(errors, NewError("Invalid column definition", input))
+		column.Span.End = newCursor(input).lineSpan().End
 	}
-	return column, errors
+	errors = append(errors, column.checkReferenceOptions()...)
+	return column, append(errors, column.checkNullable()...)
 }
 
-var aliasFormat = regexp.MustCompile(`^\s*-\s*alias\s*:\s*([^\s]+?)\s*$`)
+// skipSubLines advances past the alias, constraint and continuation lines
+// that follow an invalid column, so parsing resumes at the next column.
+func skipSubLines(input Input) {
+	for input.Scan() {
+		if kind := newCursor(input).peek().Kind; kind != lexer.Dash && kind != lexer.Pipe {
+			input.Backtrack()
+			return
+		}
+	}
+}
 
-func ParseAlias(input *Scanner) (string, []error) {
+// An alias line is `-alias: name`, where name is a single word. The name
+// may be quoted, as in `-alias: 'id'`, but must still be a single word once
+// unquoted.
+func ParseAlias(input Input) (string, []error) {
 	input.Scan()
-	matches := aliasFormat.FindStringSubmatch(input.Text())
-	if matches != nil {
-		return matches[1], nil
-	} else {
-		return "", []error{NewError("Ill-formed alias", input)}
+	c := newCursor(input)
+	if c.accept(lexer.Dash) && c.peek().Text == "alias" && c.accept(lexer.Ident) && c.accept(lexer.Colon) {
+		literal := c.peek()
+		alias := c.word()
+		if literal.Kind == lexer.String && alias == literal.Text {
+			value, err := lexer.Unquote(literal.Text)
+			if toks := lexer.Lex(value); err != nil || len(toks) != 2 || toks[0].Kind != lexer.Ident {
+				return "", []error{errorAt("Quoted alias must be a single word", literal.Pos)}
+			}
+			alias = value
+		}
+		if alias != "" && c.done() && !c.illegal() && !strings.ContainsAny(alias, `'"`) {
+			return alias, nil
+		}
 	}
+	return "", []error{NewError("Ill-formed alias", input)}
 }
 
-// Can legally match aliases, but that's a bad idea
-var constraintFormat = regexp.MustCompile(`\s*-\s*(.+?)(?:\s*:\s*(.+?))?\s*$`)
-
-func ParseConstraint(input *Scanner) (*Constraint, []error) {
+// A constraint line is `-name` or `-name: value`. Everything after the first
+// colon is the value, taken verbatim. A long value can carry on over the
+// following lines, each starting with `|`:
+//
+//	-check: price > 0
+//	  | AND price < 1000
+//
+// The lines are folded into Value, joined by single spaces, and Lines keeps
+// them as written.
+func ParseConstraint(input Input) (*Constraint, []error) {
 	input.Scan()
-	c := &Constraint{}
-	matches := constraintFormat.FindStringSubmatch(input.Text())
-	if matches != nil {
-		c.Name = matches[1]
-		c.Value = matches[2]
-		return c, nil
-	}
-	return nil, []error{NewError("Ill-formed constraint", input)}
+	c := newCursor(input)
+	if c.illegal() {
+		return nil, []error{NewError("Unterminated string literal", input)}
+	}
+	if !c.accept(lexer.Dash) {
+		return nil, []error{NewError("Ill-formed constraint", input)}
+	}
+	span := c.lineSpan()
+	dialects, err := c.trailingWhen()
+	if err != nil {
+		return nil, []error{err}
+	}
+	con := &Constraint{Name: c.until(lexer.Colon), Comment: c.comment, Span: span, Dialects: dialects}
+	if con.Name == "" {
+		return nil, []error{NewError("Ill-formed constraint", input)}
+	}
+	if con.Name == "alias" {
+		return nil, []error{NewError("Alias is not a constraint", input)}
+	}
+	hasValue := c.accept(lexer.Colon)
+	end := c.peek().Pos
+	literal := c.peek()
+	lines := []string{c.rest()}
+	if hasValue && literal.Kind == lexer.String && lines[0] == literal.Text {
+		con.Quoted = true
+	} else {
+		literal = lexer.Token{}
+	}
+
+	var errors []error
+	for input.Scan() {
+		c := newCursor(input)
+		if !c.accept(lexer.Pipe) {
+			input.Backtrack()
+			break
+		}
+		if c.illegal() {
+			errors = append(errors, NewError("Unterminated string literal", input))
+		} else if !hasValue {
+			errors = append(errors, NewError("Continuation line after a constraint with no value", input))
+		} else if con.Dialects != nil {
+			errors = append(errors, NewError("A when qualifier must end the constraint", input))
+		}
+		span := c.lineSpan()
+		var err error
+		if con.Dialects, err = c.trailingWhen(); err != nil {
+			errors = append(errors, err)
+		}
+		lines = append(lines, c.restIndented())
+		con.Span.End = span.End
+		if c.comment != "" {
+			con.Comment = strings.TrimSpace(con.Comment + " " + c.comment)
+		}
+	}
+	if errors != nil {
+		return nil, errors
+	}
+
+	if hasValue {
+		con.Raw = foldLines(lines)
+		con.Value = con.Raw
+		if con.Raw == "" {
+			return nil, []error{errorAt("Missing constraint value", end)}
+		}
+		if len(lines) > 1 {
+			con.Lines = lines
+			con.Quoted = false
+		}
+	}
+	if con.Quoted {
+		value, err := lexer.Unquote(literal.Text)
+		if err != nil {
+			return nil, []error{errorAt("Invalid escape sequence in string literal", literal.Pos)}
+		}
+		con.Value = value
+	}
+	return con, nil
+}
+
+// foldLines joins the non-blank lines of a multi-line value with single
+// spaces.
+func foldLines(lines []string) string {
+	var parts []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
 }
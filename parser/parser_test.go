@@ -11,38 +11,134 @@ func DummyScanner(text string) *Scanner {
 }
 
 func (c *Constraint) Equals(d Constraint) bool {
-	return c.Name == d.Name && c.Value == d.Value
+	res := c.Name == d.Name && c.Value == d.Value && c.Quoted == d.Quoted && c.Comment == d.Comment && c.From == d.From && len(c.Lines) == len(d.Lines) &&
+		whenString(c.Dialects) == whenString(d.Dialects)
+	if d.Raw != "" {
+		res = res && c.Raw == d.Raw
+	}
+	for i, line := range c.Lines {
+		res = res && line == d.Lines[i]
+	}
+	return res
 }
 
 func (c *Column) Equals(d Column) bool {
 	res := c.Name == d.Name &&
+	      c.Doc == d.Doc &&
+	      c.Comment == d.Comment &&
+	      c.AliasComment == d.AliasComment &&
 	      c.Type == d.Type &&
+	      typeString("", c.TypeArgs) == typeString("", d.TypeArgs) &&
+	      c.Nullable == d.Nullable &&
+	      c.From == d.From &&
+	      c.TypeName == d.TypeName &&
 		  c.RequestedType == d.RequestedType &&
+		  requestedTypeString("", c.DialectTypes) == requestedTypeString("", d.DialectTypes) &&
 		  c.Alias == d.Alias &&
 		  len(c.Constraints) == len(d.Constraints)
     for i, con := range c.Constraints {
 		res = res && con.Equals(*d.Constraints[i])
     }
-	return res
+	if d.TypeRef != nil {
+		res = res && c.TypeRef != nil && c.TypeRef.String() == d.TypeRef.String()
+	}
+	if c.Reference == nil || d.Reference == nil {
+		return res && c.Reference == d.Reference
+	}
+	ref := *c.Reference
+	ref.Span = Span{}
+	return res && ref == *d.Reference
 }
 
 func (t *Table) Equals(u Table) bool {
-	res := t.Name == u.Name && len(t.Columns) == len(u.Columns)
+	res := t.Name == u.Name && t.Namespace == u.Namespace && t.Doc == u.Doc && t.Abstract == u.Abstract &&
+		strings.Join(t.Mixins, ",") == strings.Join(u.Mixins, ",") && len(t.Columns) == len(u.Columns) && len(t.Constraints) == len(u.Constraints) && len(t.Indexes) == len(u.Indexes)
 	for i, col := range t.Columns {
 		res = res && col.Equals(*u.Columns[i])
 	}
+	for i, con := range t.Constraints {
+		res = res && con.Equals(*u.Constraints[i])
+	}
+	for i, idx := range t.Indexes {
+		res = res && idx.Equals(*u.Indexes[i])
+	}
+	return res
+}
+
+func (c *TableConstraint) Equals(d TableConstraint) bool {
+	res := c.Name == d.Name && c.Comment == d.Comment && len(c.Columns) == len(d.Columns)
+	for i, name := range c.Columns {
+		res = res && name == d.Columns[i]
+	}
 	return res
 }
 
+func (i *Index) Equals(j Index) bool {
+	res := i.Name == j.Name && i.Unique == j.Unique && i.Method == j.Method && i.Where == j.Where &&
+		i.Comment == j.Comment && len(i.Columns) == len(j.Columns)
+	for k, name := range i.Columns {
+		res = res && name == j.Columns[k]
+	}
+	return res
+}
+
+func (e *Enum) Equals(f Enum) bool {
+	res := e.Name == f.Name && e.Doc == f.Doc && e.Comment == f.Comment && len(e.Members) == len(f.Members)
+	for i, m := range e.Members {
+		n := *m
+		n.Span = Span{}
+		res = res && n == *f.Members[i]
+	}
+	return res
+}
+
+func (v *View) Equals(w View) bool {
+	res := v.Name == w.Name && v.Namespace == w.Namespace && v.Doc == w.Doc && v.Comment == w.Comment &&
+		v.SQL == w.SQL && len(v.Columns) == len(w.Columns)
+	for i, col := range v.Columns {
+		res = res && col.Equals(*w.Columns[i])
+	}
+	return res
+}
+
+func (d *Directive) Equals(e Directive) bool {
+	res := d.Name == e.Name && len(d.Items) == len(e.Items)
+	for i, item := range d.Items {
+		res = res && *item == *e.Items[i]
+	}
+	return res
+}
+
+// directives builds the Directives of an expected tree from name, value pairs.
+func directives(pairs ...string) []*Directive {
+	var out []*Directive
+	for i := 0; i < len(pairs); i += 2 {
+		out = append(out, &Directive{Name: pairs[i], Items: []*DirectiveItem{{Value: pairs[i+1]}}})
+	}
+	return out
+}
+
 func (p *ParseTree) Equals(q ParseTree) bool {
-	res := true
-	for k,v := range p.Directives {
-		w, ok := q.Directives[k]
-		res = res && ok && v == w
+	res := len(p.Directives) == len(q.Directives) && len(p.Tables) == len(q.Tables) &&
+		len(p.Mixins) == len(q.Mixins) && len(p.Enums) == len(q.Enums) && len(p.Views) == len(q.Views)
+	if !res {
+		return false
+	}
+	for i, d := range p.Directives {
+		res = res && d.Equals(*q.Directives[i])
 	}
 	for i, tab := range p.Tables {
 		res = res && tab.Equals(*q.Tables[i])
 	}
+	for i, m := range p.Mixins {
+		res = res && m.Equals(*q.Mixins[i])
+	}
+	for i, e := range p.Enums {
+		res = res && e.Equals(*q.Enums[i])
+	}
+	for i, v := range p.Views {
+		res = res && v.Equals(*q.Views[i])
+	}
 	return res
 }
 
@@ -53,7 +149,7 @@ var constraintMatchesTests = []struct{
 }{
 	{"Single Token", "-unique", Constraint{Name: "unique"}},
 	{"Multiple Tokens", "-primary key", Constraint{Name: "primary key"}},
-	{"Name With Value", "-default:'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
+	{"Name With Value", "-default:'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
 	{"Arbitrary Spacing", "- NOT NULL", Constraint{Name: "NOT NULL"}},
 	{"Arbitrary Spacing", "-\tNOT NULL", Constraint{Name: "NOT NULL"}},
 	{"Arbitrary Spacing", "- \tNOT NULL", Constraint{Name: "NOT NULL"}},
@@ -64,12 +160,67 @@ var constraintMatchesTests = []struct{
 	{"Arbitrary Spacing", "-NOT NULL\t", Constraint{Name: "NOT NULL"}},
 	{"Arbitrary Spacing", "-NOT NULL \t", Constraint{Name: "NOT NULL"}},
 	{"Arbitrary Spacing", " \t   -\t   \t  NOT NULL\t\t    ", Constraint{Name: "NOT NULL"}},
-	{"Arbitrary Spacing", "-default :'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
-	{"Arbitrary Spacing", "-default\t:'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
-	{"Arbitrary Spacing", "-default \t: 'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
-	{"Arbitrary Spacing", "-default: 'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
-	{"Arbitrary Spacing", "-default:\t'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
-	{"Arbitrary Spacing", "-default:\t 'John Doe'", Constraint{Name: "default", Value: "'John Doe'"}},
+	{"Arbitrary Spacing", "-default :'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
+	{"Arbitrary Spacing", "-default\t:'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
+	{"Arbitrary Spacing", "-default \t: 'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
+	{"Arbitrary Spacing", "-default: 'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
+	{"Arbitrary Spacing", "-default:\t'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
+	{"Arbitrary Spacing", "-default:\t 'John Doe'", Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true}},
+	{"Double Quoted Value", `-default: "say \"hi\""`, Constraint{Name: "default", Value: `say "hi"`, Raw: `"say \"hi\""`, Quoted: true}},
+	{"Escaped Value", `-default: 'caf\u00e9\n'`, Constraint{Name: "default", Value: "café\n", Raw: `'caf\u00e9\n'`, Quoted: true}},
+	{"Colon In Quoted Value", "-default: '12:30'", Constraint{Name: "default", Value: "12:30", Raw: "'12:30'", Quoted: true}},
+	{"Expression Kept Raw", "-check: name <> 'it''s'", Constraint{Name: "check", Value: "name <> 'it''s'", Raw: "name <> 'it''s'"}},
+}
+
+var continuedConstraintTests = []struct {
+	name string
+	input string
+	expected Constraint
+}{
+	{"Single Continuation", "-check: price > 0\n  | AND price < 1000", Constraint{
+		Name: "check",
+		Value: "price > 0 AND price < 1000",
+		Lines: []string{"price > 0", "AND price < 1000"},
+	}},
+	{"Value Starts On Next Line", "-default:\n| now()", Constraint{
+		Name: "default",
+		Value: "now()",
+		Lines: []string{"", "now()"},
+	}},
+	{"Indentation Kept In Lines", "-check:\n  | a > 0\n  |   OR b > 0\n  |\n  | AND c", Constraint{
+		Name: "check",
+		Value: "a > 0 OR b > 0 AND c",
+		Lines: []string{"", "a > 0", "  OR b > 0", "", "AND c"},
+	}},
+	{"Comments Combined", "-check: a > 0 # positive\n  | AND a < 9 # small", Constraint{
+		Name: "check",
+		Value: "a > 0 AND a < 9",
+		Lines: []string{"a > 0", "AND a < 9"},
+		Comment: "positive small",
+	}},
+	{"Stops At Next Constraint", "-check: a\n| b\n-unique", Constraint{
+		Name: "check",
+		Value: "a b",
+		Lines: []string{"a", "b"},
+	}},
+}
+
+func TestParseConstraintContinued(t *testing.T) {
+	for _, test := range continuedConstraintTests {
+		t.Run(test.name, func(tt *testing.T) {
+			c, errs := ParseConstraint(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !c.Equals(test.expected) {
+				tt.Fatalf("Incorrect value parsed. Expected: %s; got: %s (%q)", &test.expected, c, c.Lines)
+			}
+			again, errs := ParseConstraint(DummyScanner(c.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Lines not preserved by String. Got %q from %q (%v)", again.Lines, c.String(), errs)
+			}
+		})
+	}
 }
 
 func TestParseConstraintMatches(t *testing.T) {
@@ -80,7 +231,7 @@ func TestParseConstraintMatches(t *testing.T) {
 				tt.Fatalf("Unexpected errors: %v", errs)
 			}
 			if !c.Equals(test.expected) {
-				tt.Fatalf("Incorrect value parsed. Expected: %s; got: %s", test.expected, c)
+				tt.Fatalf("Incorrect value parsed. Expected: %s; got: %s", &test.expected, c)
 			}
 		})
 	}
@@ -93,6 +244,9 @@ var constraintRejectsTests = []struct {
 	{"Empty Line", ""},
 	{"Empty Constraint", "-"},
 	{"Non-constraint", "uid int"},
+	{"Continued Without Value", "-unique\n| a"},
+	{"Invalid Escape", `-default: '\d'`},
+	{"Continued Blank Value", "-check:\n|"},
 }
 
 func TestParseConstraintRejects(t *testing.T) {
@@ -138,6 +292,8 @@ var aliasMatchesTests = []struct {
 	{"Arbitrary Spacing", "-alias:hex\t", "hex"},
 	{"Arbitrary Spacing", "-alias:hex \t", "hex"},
 	{"Arbitrary Spacing", " \t   -   \t\t\t  alias\t\t\t\t  :\t   \t  hex\t\t   ", "hex"},
+	{"Quoted", "-alias: 'user_id'", "user_id"},
+	{"Double Quoted", "-alias: \"id\"", "id"},
 }
 
 func TestParseAliasMatches(t *testing.T) {
@@ -160,6 +316,9 @@ var aliasRejectsTests = []struct {
 	{"Without Alias", "-:name"},
 	{"Constraint", "-unique"},
 	{"Multi-word Alias", "-alias:multi word"},
+	{"Quoted Multi-word Alias", "-alias: 'my id'"},
+	{"Quoted Empty Alias", "-alias: ''"},
+	{"Quote Inside Word", "-alias: it's"},
 }
 
 func TestParseAliasRejects(t *testing.T) {
@@ -212,6 +371,12 @@ var columnMatchesTests = []struct{
 		Type: "int",
 		Alias: "user_id",
 	}},
+	{"Quoted Alias As Constraint Value", "name string\n-default: 'alias'", Column{
+		Name: "name",
+		Type: "string",
+		Constraints: []*Constraint{
+			&Constraint{Name: "default", Value: "alias", Raw: "'alias'", Quoted: true},
+	}}},
 	{"Using With Constraints", "uid int using numeric\n-foreign key\n-alias: id\n-not null", Column {
 		Name: "uid",
 		Type: "int",
@@ -221,6 +386,33 @@ var columnMatchesTests = []struct{
 			&Constraint{Name: "foreign key"},
 			&Constraint{Name: "not null"},
 	}}},
+	{"Type With Length", "name string(255)", Column{Name: "name", Type: "string", TypeArgs: []int{255}}},
+	{"Type With Precision", "price decimal(10,2) using NUMERIC(10, 2)", Column{
+		Name: "price",
+		Type: "decimal",
+		TypeArgs: []int{10, 2},
+		RequestedType: "NUMERIC(10, 2)",
+	}},
+	{"Type Arguments With Spaces", "price decimal(10, 2) # money", Column{Name: "price", Type: "decimal", TypeArgs: []int{10, 2}, Comment: "money"}},
+	{"Optional Mark", "nickname string?", Column{Name: "nickname", Type: "string", Nullable: true}},
+	{"Optional Mark After Arguments", "nickname string(32)? using VARCHAR(32)", Column{
+		Name: "nickname",
+		Type: "string",
+		TypeArgs: []int{32},
+		Nullable: true,
+		RequestedType: "VARCHAR(32)",
+	}},
+	{"Optional Keyword", "nickname optional string", Column{Name: "nickname", Type: "string", Nullable: true}},
+	{"Type Called Optional", "flag optional using BOOLEAN", Column{Name: "flag", Type: "optional", RequestedType: "BOOLEAN"}},
+	{"Null Constraint", "nickname string\n- null", Column{Name: "nickname", Type: "string", Nullable: true, Constraints: []*Constraint{
+		&Constraint{Name: "null"},
+	}}},
+	{"Optional With Null Constraint", "nickname string?\n-NULL", Column{Name: "nickname", Type: "string", Nullable: true, Constraints: []*Constraint{
+		&Constraint{Name: "NULL"},
+	}}},
+	{"Not Null", "name string\n-NOT  NULL", Column{Name: "name", Type: "string", Constraints: []*Constraint{
+		&Constraint{Name: "NOT  NULL"},
+	}}},
 }
 
 func TestParseColumnMatches(t *testing.T) {
@@ -231,7 +423,11 @@ func TestParseColumnMatches(t *testing.T) {
 				tt.Fatalf("Unexpected errors: %v", errs)
 			}
 			if !col.Equals(test.expected) {
-				tt.Fatalf("Incorrect values parsed. Expected %s; got %s", test.expected, *col)
+				tt.Fatalf("Incorrect values parsed. Expected %s; got %s", &test.expected, col)
+			}
+			again, errs := ParseColumn(DummyScanner(col.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Column not preserved by String: %s", col)
 			}
 		})
 	}
@@ -244,6 +440,17 @@ var columnRejectTests = []struct {
 	{"Incomplete Using", "id int using "},
 	{"Bad Alias", "uid int\n-alias id"},
 	{"Bad Constraint", "uid int\n-"},
+	{"Unterminated String", "name string\n-default: 'John"},
+	{"Empty Type Arguments", "name string()"},
+	{"Non-numeric Type Argument", "name string(max)"},
+	{"Negative Type Argument", "name string(-1)"},
+	{"Unterminated Type Arguments", "price decimal(10,2"},
+	{"Text After Type Arguments", "name string(255)x"},
+	{"Spaced Type Arguments", "name string (255)"},
+	{"Spaced Optional Mark", "name string ?"},
+	{"Optional And Not Null", "name string?\n- not null"},
+	{"Optional Primary Key", "id optional int\n- Primary Key"},
+	{"Null And Not Null", "name string\n-NULL\n-NOT NULL"},
 }
 
 func TestParseColumnRejects(t *testing.T) {
@@ -281,7 +488,7 @@ var tableMatchesTests = []struct {
 			}},
 			&Column{Name: "name", Type: "string", Constraints:[]*Constraint {
 				&Constraint{Name: "not null"},
-				&Constraint{Name: "default", Value: "'John Doe'"},
+				&Constraint{Name: "default", Value: "John Doe", Raw: "'John Doe'", Quoted: true},
 			}},
 		},
 	}},
@@ -302,7 +509,7 @@ func TestParseTableMatches(t *testing.T) {
 				tt.Fatalf("Unexpected errors: %v", errs)
 			}
 			if !tab.Equals(test.expected) {
-				tt.Fatalf("Incorrect values parsed. Expected %s; got %s", test.expected, *tab)
+				tt.Fatalf("Incorrect values parsed. Expected %s; got %s", &test.expected, tab)
 			}
 		})
 	}
@@ -350,15 +557,41 @@ var directiveMatchesTests = []struct {
 	{"Arbitrary Spacing", "#directive=value\t", "directive", "value"},
 	{"Arbitrary Spacing", "#directive=value \t", "directive", "value"},
 	{"Arbitrary Spacing", "#\t   \t\t\t  \tdirective\t \t =     value\t\t\t \t", "directive", "value"},
+	{"Path Value", "#include = ../shared/users.orb", "include", "../shared/users.orb"},
 }
 
 func TestParseDirectiveMatches(t *testing.T) {
 	for _,test := range directiveMatchesTests {
 		t.Run(test.name, func(tt *testing.T) {
-			directive, value, matches := ParseDirective(DummyScanner(test.input))
-			if !matches {
-				tt.Fatalf("Directive incorrectly parsed. Expected %s=%v; got %s=%v", 
-				  test.expectedName, test.expectedValue, directive, value)
+			directive, matches := ParseDirective(DummyScanner(test.input))
+			if !matches || directive.Name != test.expectedName || directive.Value() != test.expectedValue {
+				tt.Fatalf("Directive incorrectly parsed. Expected %s=%v; got %s",
+				  test.expectedName, test.expectedValue, directive)
+			}
+		})
+	}
+}
+
+var directiveValueTests = []struct {
+	name string
+	input string
+	expected string
+}{
+	{"Word", "#database = postgres", "postgres"},
+	{"Quoted", `#include = "my schemas/users.orb"`, "my schemas/users.orb"},
+	{"Single Quoted", `#version = '1.0: beta'`, "1.0: beta"},
+	{"Escapes", `#banner = "caf\u00e9 \"orb\""`, `café "orb"`},
+}
+
+func TestParseDirectiveValues(t *testing.T) {
+	for _, test := range directiveValueTests {
+		t.Run(test.name, func(tt *testing.T) {
+			d, matches := ParseDirective(DummyScanner(test.input))
+			if !matches || d.Value() != test.expected {
+				tt.Fatalf("Incorrect directive value. Expected %q; got %s (%v)", test.expected, d, matches)
+			}
+			if again, _ := ParseDirective(DummyScanner(d.String())); again == nil || again.Value() != test.expected {
+				tt.Fatalf("Value not preserved by String: %s", d)
 			}
 		})
 	}
@@ -372,12 +605,15 @@ var directiveRejectsTests = []struct {
 	{"Value With Spaces", "#directive=multiple words"},
 	{"Using : Instead of =", "#directive:value"},
 	{"Non-directive Comment", "#This is a comment"},
+	{"Unterminated Quote", `#include = "users.orb`},
+	{"Invalid Escape", `#include = "\users.orb"`},
+	{"Text After Quote", `#include = "users.orb" x`},
 }
 
 func TestParseDirectiveRejects(t *testing.T) {
 	for _,test := range directiveRejectsTests {
 		t.Run(test.name, func(tt *testing.T) {
-			_, _, matches := ParseDirective(DummyScanner(test.input))
+			_, matches := ParseDirective(DummyScanner(test.input))
 			if matches {
 				tt.Fatalf("Directive match not expected for %s", test.input)
 			}
@@ -391,11 +627,11 @@ var fileMatchesTests = []struct {
 	expected ParseTree
 } {
 	{"Empty File", "", ParseTree{}},
-	{"Single Directive", "#language=ruby", ParseTree{Directives: map[string]string{"language": "ruby"}}},
-	{"Multiple Directives", "#language=go\n#database=mysql", ParseTree{Directives: map[string]string{
-		"language": "go",
-		"database": "mysql",
-	}}},
+	{"Single Directive", "#language=ruby", ParseTree{Directives: directives("language", "ruby")}},
+	{"Multiple Directives", "#language=go\n#database=mysql", ParseTree{Directives: directives(
+		"language", "go",
+		"database", "mysql",
+	)}},
 	{"Single Empty Table", "[person]", ParseTree{Tables: []*Table{
 		&Table{Name: "person"},
 	}}},
@@ -417,7 +653,7 @@ var fileMatchesTests = []struct {
 	{"Multiple Tables With Directives", 
 	 "#language=go\n#database=sqlite\n[people]\nname string using TEXT\n\n[cars]\nvin int\nmodel string", 
 	 ParseTree{
-		 Directives: map[string]string{"language": "go", "database": "sqlite"},
+		 Directives: directives("language", "go", "database", "sqlite"),
 		 Tables: []*Table {
 			 &Table{Name: "people", Columns: []*Column {
 				 &Column{Name: "name", Type: "string", RequestedType: "TEXT"},
@@ -432,7 +668,7 @@ var fileMatchesTests = []struct {
 	{"Invalid Directives But Otherwise Fine", 
 	 "#holyeggbeaters:batman\n#life=hardknock\n[people]\nname string using TEXT\n\n[cars]\nvin int\nmodel string", 
 	 ParseTree{
-		 Directives: map[string]string{"life": "hardknock"},
+		 Directives: directives("life", "hardknock"),
 		 Tables: []*Table {
 			 &Table{Name: "people", Columns: []*Column {
 				 &Column{Name: "name", Type: "string", RequestedType: "TEXT"},
@@ -466,11 +702,11 @@ uid int
 - FOREIGN KEY: users
 - ON DELETE: CASCADE`,
      ParseTree{
-		 Directives: map[string]string{
-			 "langauge": "go",
-			 "database": "postgres",
-			 "driver": "pq",
-		 },
+		 Directives: directives(
+			 "langauge", "go",
+			 "database", "postgres",
+			 "driver", "pq",
+		 ),
 		 Tables: []*Table{
 			 &Table{Name: "users", Columns: []*Column {
 				 &Column{Name:"uid", Type:"int", RequestedType:"SERIAL", Alias:"id", Constraints: []*Constraint{
@@ -504,7 +740,7 @@ func TestParseMatches(t *testing.T) {
 				tt.Fatalf("Unexpected errors: %v", errs)
 			}
 			if !tree.Equals(test.expected) {
-				tt.Fatalf("Incorrect value parsed. Expected: %s; got: %s", test.expected, tree)
+				tt.Fatalf("Incorrect value parsed. Expected: %s; got: %s", &test.expected, tree)
 			}			
 		})
 	}
@@ -524,7 +760,7 @@ var fileRejectsTests = []struct {
 func TestParseRejects(t *testing.T) {
 	for _,test := range fileRejectsTests {
 		t.Run(test.name, func(tt *testing.T) {
-			_, _, matches := ParseDirective(DummyScanner(test.input))
+			_, matches := ParseDirective(DummyScanner(test.input))
 			if matches {
 				tt.Fatalf("Directive match not expected for %s", test.input)
 			}
@@ -532,3 +768,244 @@ func TestParseRejects(t *testing.T) {
 	}
 }
 
+
+func TestParsePositions(t *testing.T) {
+	at := func(offset, line, column int) Pos {
+		return Pos{File: "people.orb", Offset: offset, Line: line, Column: column}
+	}
+	input := "#language = go\n\n[people]\nname string\n  - NOT NULL\nage int"
+	tree, errs := ParseNamed("people.orb", strings.NewReader(input))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	tests := []struct {
+		name string
+		got Span
+		start, end Pos
+	}{
+		{"Directive", tree.Directive("language").Span,
+			at(0, 1, 1), at(14, 1, 15)},
+		{"Table", tree.Tables[0].Span,
+			at(16, 3, 1), at(57, 6, 8)},
+		{"Column", tree.Tables[0].Columns[0].Span,
+			at(25, 4, 1), at(49, 5, 13)},
+		{"Constraint", tree.Tables[0].Columns[0].Constraints[0].Span,
+			at(39, 5, 3), at(49, 5, 13)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			if test.got.Start != test.start || test.got.End != test.end {
+				tt.Fatalf("Incorrect span. Expected %+v-%+v; got %+v-%+v", test.start, test.end, test.got.Start, test.got.End)
+			}
+		})
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	_, errs := ParseNamed("bad.orb", strings.NewReader("[table]\nid int using \n"))
+	if len(errs) != 1 {
+		t.Fatalf("Expected one error; got %v", errs)
+	}
+	if msg := errs[0].Error(); !strings.HasPrefix(msg, "bad.orb:2:") {
+		t.Fatalf("Error not labelled with its position: %s", msg)
+	}
+}
+
+var recoveryTests = []struct {
+	name string
+	input string
+	expected ParseTree
+	bad []string
+}{
+	{"Bad Constraint Keeps Column", "[table]\nuid int\n-\n-unique\nname string", ParseTree{Tables: []*Table{
+		&Table{Name: "table", Columns: []*Column{
+			&Column{Name: "uid", Type: "int", Constraints: []*Constraint{
+				&Constraint{Name: "unique"},
+			}},
+			&Column{Name: "name", Type: "string"},
+		}},
+	}}, []string{"-"}},
+	{"Bad Alias Keeps Previous Alias", "[table]\nuid int\n-alias: id\n-alias id", ParseTree{Tables: []*Table{
+		&Table{Name: "table", Columns: []*Column{
+			&Column{Name: "uid", Type: "int", Alias: "id"},
+		}},
+	}}, []string{"-alias id"}},
+	{"Bad Column Skips Its Constraints", "[table]\nuid int using \n-unique\nname string\n-not null", ParseTree{Tables: []*Table{
+		&Table{Name: "table", Columns: []*Column{
+			&Column{Name: "name", Type: "string", Constraints: []*Constraint{
+				&Constraint{Name: "not null"},
+			}},
+		}},
+	}}, []string{"uid int using "}},
+	{"Bad Header Skips Table", "[bad table]\nuid int\n\n[good]\nid int", ParseTree{Tables: []*Table{
+		&Table{Name: "good", Columns: []*Column{
+			&Column{Name: "id", Type: "int"},
+		}},
+	}}, []string{"[bad table]"}},
+	{"Header Ends Table", "[first]\nid int\n[second]\nid int", ParseTree{Tables: []*Table{
+		&Table{Name: "first", Columns: []*Column{&Column{Name: "id", Type: "int"}}},
+		&Table{Name: "second", Columns: []*Column{&Column{Name: "id", Type: "int"}}},
+	}}, nil},
+	{"Continuation Without Constraint", "[table]\nid int\n| a\nname string", ParseTree{Tables: []*Table{
+		&Table{Name: "table", Columns: []*Column{
+			&Column{Name: "id", Type: "int"},
+			&Column{Name: "name", Type: "string"},
+		}},
+	}}, []string{"| a"}},
+	{"Bad Column Skips Continuations", "[table]\nid\n-check: a\n| b\nname string", ParseTree{Tables: []*Table{
+		&Table{Name: "table", Columns: []*Column{
+			&Column{Name: "name", Type: "string"},
+		}},
+	}}, []string{"id"}},
+	{"Stray Line", "[table]\nid int\n\n-primary key\n[next]", ParseTree{Tables: []*Table{
+		&Table{Name: "table", Columns: []*Column{&Column{Name: "id", Type: "int"}}},
+		&Table{Name: "next"},
+	}}, []string{"-primary key"}},
+}
+
+func badLines(tree *ParseTree) []string {
+	var out []string
+	for _, b := range tree.Bad {
+		out = append(out, b.Text)
+	}
+	for _, t := range tree.Tables {
+		for _, b := range t.Bad {
+			out = append(out, b.Text)
+		}
+		for _, c := range t.Columns {
+			for _, b := range c.Bad {
+				out = append(out, b.Text)
+			}
+		}
+	}
+	return out
+}
+
+func TestParseRecovers(t *testing.T) {
+	for _, test := range recoveryTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(test.input))
+			if len(errs) != len(test.bad) {
+				tt.Fatalf("Expected %d errors; got %v", len(test.bad), errs)
+			}
+			if len(tree.Tables) != len(test.expected.Tables) || !tree.Equals(test.expected) {
+				tt.Fatalf("Incorrect partial tree. Expected: %s; got: %s", &test.expected, tree)
+			}
+			bad := badLines(tree)
+			if strings.Join(bad, "|") != strings.Join(test.bad, "|") {
+				tt.Fatalf("Incorrect error nodes. Expected %q; got %q", test.bad, bad)
+			}
+		})
+	}
+}
+
+func TestParseReportsReadErrors(t *testing.T) {
+	input := "[people]\nname string\n-default: '" + strings.Repeat("x", 100) + "'\n"
+	tree, errs := ParseWithOptions(strings.NewReader(input), Options{Filename: "long.orb", MaxLineSize: 64})
+	if len(errs) != 1 {
+		t.Fatalf("Expected one error; got %v", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "long.orb:3:") {
+		t.Fatalf("Error not reported at the long line: %v", errs[0])
+	}
+	if len(tree.Tables) != 1 || len(tree.Tables[0].Columns) != 1 {
+		t.Fatalf("Lines before the error were not kept: %s", tree)
+	}
+}
+
+var docMatchesTests = []struct {
+	name string
+	input string
+	expected ParseTree
+}{
+	{"Table Doc", "# People we know\n[people]", ParseTree{Tables: []*Table{
+		&Table{Name: "people", Doc: "People we know"},
+	}}},
+	{"Multi-line Doc", "# People\n#   we know\n[people]", ParseTree{Tables: []*Table{
+		&Table{Name: "people", Doc: "People\n  we know"},
+	}}},
+	{"Blank Line Separates Doc", "# Not about people\n\n[people]", ParseTree{Tables: []*Table{
+		&Table{Name: "people"},
+	}}},
+	{"Directive Separates Doc", "# About the language\n#language = go\n[people]", ParseTree{
+		Directives: directives("language", "go"),
+		Tables: []*Table{&Table{Name: "people"}},
+	}},
+	{"Column Doc", "[people]\nid int\n# Full name\n# with spaces\nname string\n-not null", ParseTree{Tables: []*Table{
+		&Table{Name: "people", Columns: []*Column{
+			&Column{Name: "id", Type: "int"},
+			&Column{Name: "name", Type: "string", Doc: "Full name\nwith spaces", Constraints: []*Constraint{
+				&Constraint{Name: "not null"},
+			}},
+		}},
+	}}},
+	{"Doc Does Not Carry Over", "# First\n[first]\nid int\n# dangling\n\n[second]", ParseTree{Tables: []*Table{
+		&Table{Name: "first", Doc: "First", Columns: []*Column{&Column{Name: "id", Type: "int"}}},
+		&Table{Name: "second"},
+	}}},
+}
+
+func TestParseDocs(t *testing.T) {
+	for _, test := range docMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !tree.Equals(test.expected) {
+				tt.Fatalf("Incorrect value parsed. Expected: %s; got: %s", &test.expected, tree)
+			}
+			again, _ := Parse(strings.NewReader(tree.String()))
+			if !again.Equals(test.expected) {
+				tt.Fatalf("Docs not preserved by String. Got: %s", again)
+			}
+		})
+	}
+}
+
+var commentMatchesTests = []struct {
+	name string
+	input string
+	expected Column
+}{
+	{"Column Comment", "name string using TEXT # display name", Column{
+		Name: "name", Type: "string", RequestedType: "TEXT", Comment: "display name",
+	}},
+	{"Comment Without Space", "name string#display name", Column{
+		Name: "name", Type: "string", Comment: "display name",
+	}},
+	{"Constraint Comment", "name string\n-default: 'x' # placeholder", Column{
+		Name: "name", Type: "string", Constraints: []*Constraint{
+			&Constraint{Name: "default", Value: "x", Raw: "'x'", Quoted: true, Comment: "placeholder"},
+		},
+	}},
+	{"Alias Comment", "uid int\n-alias: id # for the API", Column{
+		Name: "uid", Type: "int", Alias: "id", AliasComment: "for the API",
+	}},
+	{"Escaped Hash", "tag string using CHAR(1)\n-check: tag <> '#' AND id \\# 2 = 0 # hash", Column{
+		Name: "tag", Type: "string", RequestedType: "CHAR(1)", Constraints: []*Constraint{
+			&Constraint{Name: "check", Value: "tag <> '#' AND id # 2 = 0", Comment: "hash"},
+		},
+	}},
+	{"Escaped Hash In Requested Type", "tag string using a\\#b", Column{
+		Name: "tag", Type: "string", RequestedType: "a#b",
+	}},
+}
+
+func TestParseComments(t *testing.T) {
+	for _, test := range commentMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !col.Equals(test.expected) {
+				tt.Fatalf("Incorrect values parsed. Expected %s; got %s", &test.expected, col)
+			}
+			again, errs := ParseColumn(DummyScanner(col.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Comments not preserved by String. Got %s from %q (%v)", again, col.String(), errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Cardinality is the kind of relationship a reference describes, seen from
+// the referencing table.
+type Cardinality string
+
+const (
+	BelongsTo Cardinality = "belongs-to"
+	HasOne    Cardinality = "has-one"
+	HasMany   Cardinality = "has-many"
+)
+
+// Reference is a foreign key from a column to a column of another table. It
+// is written as special constraint lines under the column:
+//
+//	student_id int
+//	-references: student.sid
+//	-on delete: cascade
+//	-on update: restrict
+//	-relationship: belongs-to
+//
+// Only the references line is required, and it must come first. Without a
+// relationship line, the cardinality is BelongsTo.
+type Reference struct {
+	// Table can be qualified by a namespace, as in auth.user.
+	Table string
+	Column string
+	// OnDelete and OnUpdate are the referential actions, such as cascade or
+	// set null, in lower case. "" leaves the choice to the database.
+	OnDelete string
+	OnUpdate string
+	Cardinality Cardinality
+	// Comment is the trailing comment on the references line.
+	Comment string
+	// Span covers the references line.
+	Span Span
+}
+
+func (r *Reference) String() string {
+	s := "-references: " + r.Table + "." + r.Column + commentString(r.Comment)
+	if r.OnDelete != "" {
+		s += "\n-on delete: " + r.OnDelete
+	}
+	if r.OnUpdate != "" {
+		s += "\n-on update: " + r.OnUpdate
+	}
+	if r.Cardinality != BelongsTo {
+		s += "\n-relationship: " + string(r.Cardinality)
+	}
+	return s
+}
+
+// isReference reports whether con is one of the lines of a reference for
+// column c. The options only count after a references line; before one,
+// they are ordinary constraints, as in
+//
+//	-FOREIGN KEY: users
+//	-ON DELETE: CASCADE
+func (c *Column) isReference(con *Constraint) bool {
+	switch referenceLine(con) {
+	case "references":
+		return true
+	case "on delete", "on update", "relationship":
+		return c.Reference != nil
+	}
+	return false
+}
+
+// referenceLine returns the name of con in lower case with single spaces,
+// as the names of reference lines are written.
+func referenceLine(con *Constraint) string {
+	return strings.ToLower(strings.Join(strings.Fields(con.Name), " "))
+}
+
+// checkReferenceOptions reports option lines that came before the column's
+// references line, and so were kept as ordinary constraints while the
+// reference went without them.
+func (c *Column) checkReferenceOptions() []error {
+	if c.Reference == nil {
+		return nil
+	}
+	var errors []error
+	for _, con := range c.Constraints {
+		switch referenceLine(con) {
+		case "on delete", "on update", "relationship":
+			errors = append(errors, errorAt("The "+con.Name+" line must come after the references line", con.Span.Start))
+		}
+	}
+	return errors
+}
+
+var referentialActions = map[string]bool{
+	"cascade": true,
+	"restrict": true,
+	"set null": true,
+	"set default": true,
+	"no action": true,
+}
+
+var cardinalities = map[Cardinality]bool{
+	BelongsTo: true,
+	HasOne: true,
+	HasMany: true,
+}
+
+// addReference applies a reference line, already read as a constraint, to
+// the column's Reference. A reference holds for every dialect, so its lines
+// can't have a `[when ...]` qualifier.
+func (c *Column) addReference(con *Constraint) error {
+	value := strings.ToLower(strings.Join(strings.Fields(con.Value), " "))
+	if value == "" {
+		return errorAt("Missing value for "+con.Name, con.Span.Start)
+	}
+	if con.Dialects != nil {
+		return errorAt("A "+strings.ToLower(con.Name)+" line applies to every dialect, and can't have a when qualifier", con.Span.Start)
+	}
+	r := c.Reference
+	switch referenceLine(con) {
+	case "references":
+		if r != nil {
+			return errorAt("Column "+c.Name+" already references "+r.Table+"."+r.Column, con.Span.Start)
+		}
+		i := strings.LastIndex(con.Value, ".")
+		table, column := con.Value[:i+1], con.Value[i+1:]
+		table = strings.TrimSuffix(table, ".")
+		if _, _, ok := splitTableName(table); i < 0 || !ok || column == "" || strings.ContainsAny(con.Value, " \t") {
+			return errorAt("Reference must be of the form table.column", con.Span.Start)
+		}
+		c.Reference = &Reference{Table: table, Column: column, Cardinality: BelongsTo, Comment: con.Comment, Span: con.Span}
+	case "on delete", "on update":
+		if !referentialActions[value] {
+			return errorAt("Unknown referential action "+con.Value, con.Span.Start)
+		}
+		if referenceLine(con) == "on delete" {
+			r.OnDelete = value
+		} else {
+			r.OnUpdate = value
+		}
+	case "relationship":
+		if !cardinalities[Cardinality(value)] {
+			return errorAt("Unknown relationship "+con.Value+", expected belongs-to, has-one or has-many", con.Span.Start)
+		}
+		r.Cardinality = Cardinality(value)
+	}
+	return nil
+}
+
+// Table returns the table with the given name, or nil if there is none. A
+// qualified name, such as auth.user, must match the table's namespace too;
+// an unqualified one matches a table of that name in any namespace, as long
+// as there is only one.
+func (p *ParseTree) Table(name string) *Table {
+	if tables := p.tablesNamed(name); len(tables) == 1 {
+		return tables[0]
+	}
+	return nil
+}
+
+// tablesNamed returns the tables that a name can refer to: the first table
+// whose qualified name it is, or failing that, if the name is unqualified,
+// every table of that name.
+func (p *ParseTree) tablesNamed(name string) []*Table {
+	for _, t := range p.Tables {
+		if t.QualifiedName() == name {
+			return []*Table{t}
+		}
+	}
+	if strings.Contains(name, ".") {
+		return nil
+	}
+	var tables []*Table
+	for _, t := range p.Tables {
+		if t.Name == name {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// Resolve expands named types and applies the mixins of every table, then
+// checks that every reference in the tree, views included, points at a
+// table and column that exist, and that no enum is declared twice. ParseFile resolves the tree
+// once every included file has been merged in, and Parse resolves it if it
+// has no includes. Resolving a tree more than once has no further effect on
+// it.
+func (p *ParseTree) Resolve() []error {
+	return p.resolve(false)
+}
+
+// resolve is Resolve. If partial is set, the tree is missing the files it
+// includes, so tables with unknown mixins are left alone, and references to
+// unknown tables aren't reported.
+func (p *ParseTree) resolve(partial bool) []error {
+	errors := p.expandTypes()
+	errors = append(errors, p.resolveMixins(partial)...)
+	for _, e := range p.Enums {
+		if first := p.Enum(e.Name); first != e {
+			errors = append(errors, errorAt("Enum "+e.Name+" is already declared at "+first.Span.Start.String(), e.Span.Start))
+		}
+	}
+	for _, t := range p.Tables {
+		if first := p.tablesNamed(t.QualifiedName())[0]; first != t {
+			errors = append(errors, errorAt("Table "+t.QualifiedName()+" is already declared at "+first.Span.Start.String(), t.Span.Start))
+		}
+	}
+	for _, v := range p.Views {
+		if first := p.View(v.QualifiedName()); first != v {
+			errors = append(errors, errorAt("View "+v.Name+" is already declared at "+first.Span.Start.String(), v.Span.Start))
+		} else if t := p.Table(v.QualifiedName()); t != nil && t.QualifiedName() == v.QualifiedName() {
+			errors = append(errors, errorAt("View "+v.Name+" has the same name as the table at "+t.Span.Start.String(), v.Span.Start))
+		}
+	}
+	// inherited columns are checked once, in the mixin that declares them
+	for _, col := range p.columns() {
+		r := col.Reference
+		if r == nil || col.From != "" {
+			continue
+		}
+		targets := p.tablesNamed(r.Table)
+		if len(targets) > 1 {
+			var names []string
+			for _, t := range targets {
+				names = append(names, t.QualifiedName())
+			}
+			errors = append(errors, errorAt("Reference to table "+r.Table+" is ambiguous between "+strings.Join(names, " and "), r.Span.Start))
+			continue
+		}
+		target := p.Table(r.Table)
+		if target == nil && partial {
+			continue
+		}
+		if target == nil {
+			errors = append(errors, errorAt("Reference to unknown table "+r.Table, r.Span.Start))
+		} else if target.Column(r.Column) == nil {
+			errors = append(errors, errorAt("Reference to unknown column "+r.Column+" of table "+r.Table, r.Span.Start))
+		}
+	}
+	return errors
+}
+
+// columns returns the columns of every mixin, table and view in the tree.
+func (p *ParseTree) columns() []*Column {
+	var columns []*Column
+	for _, t := range append(append([]*Table{}, p.Mixins...), p.Tables...) {
+		columns = append(columns, t.Columns...)
+	}
+	for _, v := range p.Views {
+		columns = append(columns, v.Columns...)
+	}
+	return columns
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var referenceMatchesTests = []struct {
+	name string
+	input string
+	expected Column
+}{
+	{"Target Only", "student_id int\n-references: student.sid", Column{
+		Name: "student_id", Type: "int",
+		Reference: &Reference{Table: "student", Column: "sid", Cardinality: BelongsTo},
+	}},
+	{"Actions", "student_id int\n- references: student.sid # owner\n- on delete: CASCADE\n- On Update: set  null", Column{
+		Name: "student_id", Type: "int",
+		Reference: &Reference{Table: "student", Column: "sid", OnDelete: "cascade", OnUpdate: "set null", Cardinality: BelongsTo, Comment: "owner"},
+	}},
+	{"Cardinality", "profile_id int\n-references: profile.id\n-relationship: has-one\n-NOT NULL", Column{
+		Name: "profile_id", Type: "int",
+		Reference: &Reference{Table: "profile", Column: "id", Cardinality: HasOne},
+		Constraints: []*Constraint{{Name: "NOT NULL"}},
+	}},
+	{"Free Text Foreign Key", "uid int\n-FOREIGN KEY: users\n-ON DELETE: CASCADE", Column{
+		Name: "uid", Type: "int",
+		Constraints: []*Constraint{
+			{Name: "FOREIGN KEY", Value: "users"},
+			{Name: "ON DELETE", Value: "CASCADE"},
+		},
+	}},
+	{"Free Text Before Reference", "uid int\n-FOREIGN KEY: users\n-NOT NULL\n-references: users.id\n-on update: cascade", Column{
+		Name: "uid", Type: "int",
+		Reference: &Reference{Table: "users", Column: "id", OnUpdate: "cascade", Cardinality: BelongsTo},
+		Constraints: []*Constraint{
+			{Name: "FOREIGN KEY", Value: "users"},
+			{Name: "NOT NULL"},
+		},
+	}},
+	{"Option Spacing", "uid int\n-references: users.id\n-ON  DELETE: cascade\n-On Update: set  null", Column{
+		Name: "uid", Type: "int",
+		Reference: &Reference{Table: "users", Column: "id", OnDelete: "cascade", OnUpdate: "set null", Cardinality: BelongsTo},
+	}},
+}
+
+func TestReferenceKeepsItsPlace(t *testing.T) {
+	input := "uid int\n-FOREIGN KEY: users\n-references: users.id\n-on delete: cascade\n-NOT NULL"
+	col, errs := ParseColumn(DummyScanner(input))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if col.String() != input {
+		t.Fatalf("Reference moved by String. Expected:\n%s\ngot:\n%s", input, col)
+	}
+}
+
+func TestReferenceOptionBeforeReference(t *testing.T) {
+	col, errs := ParseColumn(DummyScanner("uid int\n-ON DELETE: CASCADE\n-references: users.id"))
+	expected := "2:1:The ON DELETE line must come after the references line"
+	if len(errs) != 1 || errs[0].Error() != expected {
+		t.Fatalf("Expected error %q; got %v", expected, errs)
+	}
+	if col == nil || col.Reference == nil {
+		t.Fatalf("Column and reference should be kept: %v", col)
+	}
+}
+
+func TestParseReferenceMatches(t *testing.T) {
+	for _, test := range referenceMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !col.Equals(test.expected) {
+				tt.Fatalf("Incorrect column. Expected %s; got %s", &test.expected, col)
+			}
+			again, errs := ParseColumn(DummyScanner(col.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Column not preserved by String: %s", col)
+			}
+		})
+	}
+}
+
+var referenceRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Missing Column", "a int\n-references: student", "table.column"},
+	{"Too Many Parts", "a int\n-references: district.school.student.sid", "table.column"},
+	{"Missing Target", "a int\n-references", "Missing value for references"},
+	{"Unknown Action", "a int\n-references: student.sid\n-on delete: explode", "Unknown referential action explode"},
+	{"Unknown Relationship", "a int\n-references: student.sid\n-relationship: many-to-many", "Unknown relationship many-to-many"},
+	{"Dialect Qualifier", "a int\n-references: student.sid [when postgres]", "references line applies to every dialect"},
+	{"Dialect Qualifier On Option", "a int\n-references: student.sid\n-on delete: cascade [when sqlite]", "on delete line applies to every dialect"},
+	{"Second Reference", "a int\n-references: student.sid\n-references: course.cid", "already references student.sid"},
+}
+
+func TestParseReferenceRejects(t *testing.T) {
+	for _, test := range referenceRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.message) {
+				tt.Fatalf("Expected one %q error; got %v", test.message, errs)
+			}
+			if col == nil || len(col.Bad) != 1 {
+				tt.Fatalf("Column should be kept with one bad line: %v", col)
+			}
+		})
+	}
+}
+
+var resolveTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Resolved", "[student]\nsid int\n\n[enrolment]\nstudent int\n-references: student.sid", ""},
+	{"Resolved Before Target", "[enrolment]\nstudent int\n-references: student.sid\n\n[student]\nsid int", ""},
+	{"Self Reference", "[person]\nid int\nparent int\n-references: person.id", ""},
+	{"Unknown Table", "[enrolment]\nstudent int\n-references: pupil.sid", "3:1:Reference to unknown table pupil"},
+	{"Unknown Column", "[student]\nsid int\n\n[enrolment]\nstudent int\n-references: student.id", "6:1:Reference to unknown column id of table student"},
+	{"Unresolved With Includes", "#include = student.orb\n[enrolment]\nstudent int\n-references: student.sid", ""},
+	{"Unknown Column With Includes", "#include = other.orb\n[student]\nsid int\n\n[enrolment]\nstudent int\n-references: student.id", "7:1:Reference to unknown column id of table student"},
+}
+
+func TestResolve(t *testing.T) {
+	for _, test := range resolveTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := Parse(strings.NewReader(test.input))
+			if test.message == "" {
+				if errs != nil {
+					tt.Fatalf("Unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+func TestResolveAcrossIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.orb": "#include = student.orb, enrolment.orb\n[course]\ncid int",
+		"student.orb": "[student]\nsid int",
+		"enrolment.orb": "[enrolment]\nstudent int\n-references: student.sid\ncourse int\n-references: course.cid\nroom int\n-references: room.rid",
+	})
+	_, errs := ParseFile(filepath.Join(dir, "main.orb"))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "enrolment.orb:7:1:Reference to unknown table room") {
+		t.Fatalf("Expected one unknown table error; got %v", errs)
+	}
+}
+
+func TestResolveWithoutIncludedFiles(t *testing.T) {
+	input := "#include = common.orb\n#type email = string(320)\n[student : timestamps]\naddress email"
+	tree, errs := Parse(strings.NewReader(input))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if col := tree.Table("student").Column("address"); col.TypeName != "email" {
+		t.Fatalf("Named type not expanded: %s", col)
+	}
+	expected := "1:1:Included file common.orb is only read by ParseFile"
+	if len(tree.Warnings) != 1 || tree.Warnings[0].String() != expected {
+		t.Fatalf("Expected warning %q; got %v", expected, tree.Warnings)
+	}
+}
@@ -2,28 +2,95 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
 	"io"
 )
 
+// Input is a source of lines for the parser. Besides reading forward, it
+// must be able to Backtrack by one line, which is all the lookahead the
+// parser needs.
+type Input interface {
+	Scan() bool
+	Text() string
+	Backtrack() bool
+	Line() int
+	Pos() Pos
+	EOF() bool
+	// Err returns the error, if any, that stopped the input early. Running
+	// out of input is not an error.
+	Err() error
+}
+
+// DefaultMaxLineSize is the longest line, in bytes including the line
+// terminator, that the scanners accept unless told otherwise.
+const DefaultMaxLineSize = bufio.MaxScanTokenSize
+
+func newLineScanner(reader io.Reader, maxLineSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanLines)
+	initial := 4096
+	if maxLineSize < initial {
+		initial = maxLineSize
+	}
+	scanner.Buffer(make([]byte, 0, initial), maxLineSize)
+	return scanner
+}
+
+// Scanner reads all of its input up front, and can backtrack to any line.
 type Scanner struct {
 	buffer []string
+	// offsets[i] is the byte offset of the start of buffer[i]
+	offsets []int
+	size int
 	line int
+	file string
+	err error
 }
 
 func NewScanner(reader io.Reader) *Scanner {
+	return NewScannerSize(reader, DefaultMaxLineSize)
+}
+
+// NewScannerSize is NewScanner with a limit on the length of a line. Reading
+// stops at the first line longer than maxLineSize, and Err reports it.
+func NewScannerSize(reader io.Reader, maxLineSize int) *Scanner {
 	s := &Scanner{}
-	scanner := bufio.NewScanner(reader)
+	scanner := newLineScanner(reader, maxLineSize)
 	for scanner.Scan() {
-		s.buffer = append(s.buffer, scanner.Text())
+		raw := scanner.Bytes()
+		s.buffer = append(s.buffer, string(trimEOL(raw)))
+		s.offsets = append(s.offsets, s.size)
+		s.size += len(raw)
 	}
+	s.err = scanner.Err()
 	return s
 }
 
+// scanLines is bufio.ScanLines, except that it keeps the line terminator so
+// the scanner can keep exact byte offsets.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func trimEOL(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
+
 func (s *Scanner) String() string {
 	out := ""
 	for i,line := range s.buffer {
 		if i == s.line - 1 {
-			out += "* "	
+			out += "* "
 		} else {
 			out += "  "
 		}
@@ -60,6 +127,131 @@ func (s *Scanner) Line() int {
 	return s.line
 }
 
+// Pos returns the position of the start of the current line.
+func (s *Scanner) Pos() Pos {
+	offset := 0
+	if s.EOF() {
+		offset = s.size
+	} else if s.line > 0 {
+		offset = s.offsets[s.line - 1]
+	}
+	return Pos{File: s.file, Offset: offset, Line: s.line, Column: 1}
+}
+
 func (s *Scanner) EOF() bool {
 	return s.line > len(s.buffer)
 }
+
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// history is the number of lines a StreamScanner keeps for Backtrack.
+const history = 4
+
+type streamLine struct {
+	text string
+	offset int
+}
+
+// StreamScanner reads its input one line at a time, keeping only the last
+// few lines so that it can Backtrack. Memory use doesn't grow with the size
+// of the input.
+type StreamScanner struct {
+	scanner *bufio.Scanner
+	recent [history]streamLine
+	// read is the number of lines read from scanner so far
+	read int
+	size int
+	line int
+	file string
+}
+
+func NewStreamScanner(reader io.Reader) *StreamScanner {
+	return NewStreamScannerSize(reader, DefaultMaxLineSize)
+}
+
+// NewStreamScannerSize is NewStreamScanner with a limit on the length of a
+// line. Reading stops at the first line longer than maxLineSize, and Err
+// reports it.
+func NewStreamScannerSize(reader io.Reader, maxLineSize int) *StreamScanner {
+	return &StreamScanner{scanner: newLineScanner(reader, maxLineSize)}
+}
+
+func (s *StreamScanner) String() string {
+	out := ""
+	first := s.read - history + 1
+	if first < 1 {
+		first = 1
+	}
+	for n := first; n <= s.read; n++ {
+		if n == s.line {
+			out += "* "
+		} else {
+			out += "  "
+		}
+		out += s.recent[(n - 1) % history].text
+		if n != s.read {
+			out += "\n"
+		}
+	}
+	return out
+}
+
+func (s *StreamScanner) Scan() bool {
+	if s.line < s.read {
+		s.line += 1
+		return true
+	}
+	if s.line > s.read {
+		return false
+	}
+	if s.scanner.Scan() {
+		raw := s.scanner.Bytes()
+		s.recent[s.read % history] = streamLine{string(trimEOL(raw)), s.size}
+		s.size += len(raw)
+		s.read += 1
+	}
+	s.line += 1
+	return s.line <= s.read
+}
+
+func (s *StreamScanner) Text() string {
+	if s.line == 0 || s.EOF() {
+		return ""
+	}
+	return s.recent[(s.line - 1) % history].text
+}
+
+// Backtrack steps back one line. It fails, leaving the scanner where it was,
+// if that line has already been dropped from the history.
+func (s *StreamScanner) Backtrack() bool {
+	if s.line > 0 && s.line - 1 > s.read - history {
+		s.line -= 1
+		return s.line > 0
+	}
+	return false
+}
+
+func (s *StreamScanner) Line() int {
+	return s.line
+}
+
+// Pos returns the position of the start of the current line.
+func (s *StreamScanner) Pos() Pos {
+	offset := 0
+	if s.EOF() {
+		offset = s.size
+	} else if s.line > 0 {
+		offset = s.recent[(s.line - 1) % history].offset
+	}
+	return Pos{File: s.file, Offset: offset, Line: s.line, Column: 1}
+}
+
+func (s *StreamScanner) EOF() bool {
+	return s.line > s.read
+}
+
+func (s *StreamScanner) Err() error {
+	return s.scanner.Err()
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Each step is a Scan ('s') or a Backtrack ('b'). Both scanners should agree
+// on every result along the way.
+var scannerAgreesTests = []struct {
+	name string
+	input string
+	steps string
+}{
+	{"Empty Input", "", "ssbs"},
+	{"Forward Only", "a\nb\nc", "sssss"},
+	{"Backtrack Each Line", "a\nb\n\nc\n", "sbssbssbssbsss"},
+	{"Backtrack At EOF", "a\nb", "sssbbs"},
+	{"Windows Line Endings", "a\r\nb\r\n", "ssbss"},
+}
+
+func TestStreamScannerAgrees(t *testing.T) {
+	for _, test := range scannerAgreesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			buffered := NewScanner(strings.NewReader(test.input))
+			stream := NewStreamScanner(strings.NewReader(test.input))
+			for i, step := range test.steps {
+				var a, b bool
+				if step == 's' {
+					a, b = buffered.Scan(), stream.Scan()
+				} else {
+					a, b = buffered.Backtrack(), stream.Backtrack()
+				}
+				if a != b || buffered.Text() != stream.Text() || buffered.Pos() != stream.Pos() || buffered.EOF() != stream.EOF() {
+					tt.Fatalf("Scanners disagree at step %d (%c): %v %q %v; %v %q %v", i, step,
+						a, buffered.Text(), buffered.Pos(), b, stream.Text(), stream.Pos())
+				}
+			}
+		})
+	}
+}
+
+func TestStreamScannerHistory(t *testing.T) {
+	s := NewStreamScanner(strings.NewReader("1\n2\n3\n4\n5\n6"))
+	for s.Scan() {
+	}
+	for i := 0; i < history; i++ {
+		if !s.Backtrack() {
+			t.Fatalf("Backtrack %d failed within history", i+1)
+		}
+	}
+	if s.Backtrack() {
+		t.Fatalf("Backtrack past history succeeded")
+	}
+	if s.Text() != "3" {
+		t.Fatalf("Incorrect line after failed backtrack: %q", s.Text())
+	}
+}
+
+// failingReader returns its text, then fails instead of reporting EOF.
+type failingReader struct {
+	text string
+}
+
+var errDisk = errors.New("disk on fire")
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.text == "" {
+		return 0, errDisk
+	}
+	n := copy(p, r.text)
+	r.text = r.text[n:]
+	return n, nil
+}
+
+var scannerErrTests = []struct {
+	name string
+	input func() io.Reader
+	maxLineSize int
+	expected error
+	lines int
+}{
+	{"Clean Input", func() io.Reader { return strings.NewReader("a\nb") }, 16, nil, 2},
+	{"Read Failure", func() io.Reader { return &failingReader{"a\nb\n"} }, 16, errDisk, 2},
+	{"Line Too Long", func() io.Reader { return strings.NewReader("a\n" + strings.Repeat("b", 32) + "\nc") }, 16, bufio.ErrTooLong, 1},
+}
+
+func TestScannerErr(t *testing.T) {
+	for _, test := range scannerErrTests {
+		t.Run(test.name, func(tt *testing.T) {
+			inputs := []Input{
+				NewScannerSize(test.input(), test.maxLineSize),
+				NewStreamScannerSize(test.input(), test.maxLineSize),
+			}
+			for _, input := range inputs {
+				lines := 0
+				for input.Scan() {
+					lines += 1
+				}
+				if lines != test.lines || !errors.Is(input.Err(), test.expected) {
+					tt.Fatalf("%T read %d lines with error %v; expected %d lines with %v",
+						input, lines, input.Err(), test.lines, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func largeSchema(tables int) string {
+	var b strings.Builder
+	b.WriteString("#language = go\n#database = postgres\n")
+	for i := 0; i < tables; i++ {
+		fmt.Fprintf(&b, "\n[table_%d]\n", i)
+		b.WriteString("id int using SERIAL\n- primary key\n- alias: uid\n")
+		b.WriteString("name string using TEXT\n- NOT NULL\n- default: 'unnamed'\n")
+		b.WriteString("created_at time using TIMESTAMP WITH TIME ZONE\n- NOT NULL\n")
+	}
+	return b.String()
+}
+
+func drain(input Input) {
+	for input.Scan() {
+		_ = input.Text()
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	src := largeSchema(1000)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(NewScanner(strings.NewReader(src)))
+	}
+}
+
+func BenchmarkStreamScanner(b *testing.B) {
+	src := largeSchema(1000)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(NewStreamScanner(strings.NewReader(src)))
+	}
+}
+
+func benchmarkParse(b *testing.B, newInput func(string) Input) {
+	src := largeSchema(1000)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseInput(newInput(src))
+	}
+}
+
+func BenchmarkParseBuffered(b *testing.B) {
+	benchmarkParse(b, func(src string) Input {
+		return NewScanner(strings.NewReader(src))
+	})
+}
+
+func BenchmarkParseStreaming(b *testing.B) {
+	benchmarkParse(b, func(src string) Input {
+		return NewStreamScanner(strings.NewReader(src))
+	})
+}
@@ -0,0 +1,237 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// TableConstraint is a constraint over one or more columns of a table,
+// written on its own line starting with `!`:
+//
+//	!primary key(student, course)
+//	!unique(email, tenant)
+//
+// Columns must name columns of the same table.
+type TableConstraint struct {
+	Name string
+	Columns []string
+	// Comment is the trailing comment on the constraint line.
+	Comment string
+	Span Span
+	// From is the mixin the constraint was copied from, or "".
+	From string
+}
+
+func (c *TableConstraint) String() string {
+	return "!" + c.Name + "(" + strings.Join(c.Columns, ", ") + ")" + commentString(c.Comment)
+}
+
+// ParseTableConstraint reads a line of the form `!name(column, ...)`. The
+// name is everything before the parenthesis, such as `primary key`.
+func ParseTableConstraint(input Input) (*TableConstraint, []error) {
+	input.Scan()
+	c := newCursor(input)
+	if c.illegal() || !c.accept(lexer.Bang) {
+		return nil, []error{NewError("Ill-formed table constraint", input)}
+	}
+	con := &TableConstraint{Name: c.until(lexer.LParen), Comment: c.comment, Span: c.lineSpan()}
+	if con.Name == "" {
+		return nil, []error{NewError("Ill-formed table constraint", input)}
+	}
+	columns, err := parseColumnList(c)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if !c.done() {
+		return nil, []error{errorAt("Unexpected text after table constraint", c.peek().Pos)}
+	}
+	con.Columns = columns
+	return con, nil
+}
+
+// Index is an index on a table, written on its own line starting with `!`:
+//
+//	!index by_email(email)
+//	!unique index active_email(email, tenant) using btree where deleted_at IS NULL
+//
+// The name is optional. Where is a predicate for a partial index, taken
+// verbatim to the end of the line.
+type Index struct {
+	Name string
+	Columns []string
+	Unique bool
+	// Method is the index method, such as btree, hash or gin, or "" for the
+	// database's default.
+	Method string
+	Where string
+	// Comment is the trailing comment on the index line.
+	Comment string
+	Span Span
+	// From is the mixin the index was copied from, or "".
+	From string
+}
+
+func (i *Index) String() string {
+	s := "!index"
+	if i.Unique {
+		s = "!unique index"
+	}
+	if i.Name != "" {
+		s += " " + i.Name
+	}
+	s += "(" + strings.Join(i.Columns, ", ") + ")"
+	if i.Method != "" {
+		s += " using " + i.Method
+	}
+	if i.Where != "" {
+		s += " where " + escapeComments(i.Where)
+	}
+	return s + commentString(i.Comment)
+}
+
+// indexMethods are the index methods that can be requested.
+var indexMethods = map[string]bool{
+	"btree": true,
+	"hash": true,
+	"gin": true,
+	"gist": true,
+	"brin": true,
+	"spgist": true,
+}
+
+// isIndex reports whether a `!` line declares an index rather than a table
+// constraint.
+func isIndex(c *cursor) bool {
+	i := c.i + 1
+	if keyword(c.toks[i], "unique") {
+		i += 1
+	}
+	return keyword(c.toks[i], "index")
+}
+
+// keyword reports whether tok is the given word, ignoring case.
+func keyword(tok lexer.Token, word string) bool {
+	return tok.Kind == lexer.Ident && strings.EqualFold(tok.Text, word)
+}
+
+// ParseIndex reads a line of the form
+// `![unique] index [name](column, ...) [using method] [where predicate]`.
+func ParseIndex(input Input) (*Index, []error) {
+	input.Scan()
+	c := newCursor(input)
+	if c.illegal() || !c.accept(lexer.Bang) {
+		return nil, []error{NewError("Ill-formed index", input)}
+	}
+	idx := &Index{Comment: c.comment, Span: c.lineSpan()}
+	if keyword(c.peek(), "unique") {
+		c.next()
+		idx.Unique = true
+	}
+	if !keyword(c.peek(), "index") {
+		return nil, []error{NewError("Ill-formed index", input)}
+	}
+	c.next()
+	if c.peek().Kind == lexer.Ident {
+		idx.Name = c.next().Text
+	}
+	columns, err := parseColumnList(c)
+	if err != nil {
+		return nil, []error{err}
+	}
+	idx.Columns = columns
+	if c.accept(lexer.Using) {
+		method := c.peek()
+		if !c.accept(lexer.Ident) {
+			return nil, []error{errorAt("Missing index method", method.Pos)}
+		}
+		if !indexMethods[strings.ToLower(method.Text)] {
+			return nil, []error{errorAt("Unknown index method "+method.Text, method.Pos)}
+		}
+		idx.Method = strings.ToLower(method.Text)
+	}
+	if keyword(c.peek(), "where") {
+		end := c.next().End()
+		idx.Where = c.rest()
+		if idx.Where == "" {
+			return nil, []error{errorAt("Missing index predicate", end)}
+		}
+	}
+	if !c.done() {
+		return nil, []error{errorAt("Unexpected text after index", c.peek().Pos)}
+	}
+	return idx, nil
+}
+
+// parseColumnList reads a parenthesised, comma-separated list of column
+// names.
+func parseColumnList(c *cursor) ([]string, error) {
+	if !c.accept(lexer.LParen) {
+		return nil, errorAt("Missing column list", c.peek().Pos)
+	}
+	var columns []string
+	for {
+		name := c.peek()
+		if !c.accept(lexer.Ident) {
+			return nil, errorAt("Invalid column name in column list", name.Pos)
+		}
+		columns = append(columns, name.Text)
+		if !c.accept(lexer.Comma) {
+			break
+		}
+	}
+	if !c.accept(lexer.RParen) {
+		return nil, errorAt("Unterminated column list", c.peek().Pos)
+	}
+	return columns, nil
+}
+
+// checkColumns reports table constraints and indexes that name columns the
+// table doesn't have, or name the same column twice, and indexes that share
+// a name.
+func (t *Table) checkColumns() []error {
+	var errors []error
+	for _, con := range t.Constraints {
+		errors = append(errors, t.checkColumnList(con.Name, con.Columns, con.Span.Start)...)
+	}
+	names := map[string]bool{}
+	for _, idx := range t.Indexes {
+		what := "index"
+		if idx.Name != "" {
+			what += " " + idx.Name
+			if names[idx.Name] {
+				errors = append(errors, errorAt("Index "+idx.Name+" is declared more than once in table "+t.Name, idx.Span.Start))
+			}
+			names[idx.Name] = true
+		}
+		errors = append(errors, t.checkColumnList(what, idx.Columns, idx.Span.Start)...)
+	}
+	return errors
+}
+
+// checkColumnList checks the columns named by a constraint or index,
+// described by what.
+func (t *Table) checkColumnList(what string, columns []string, pos Pos) []error {
+	var errors []error
+	seen := map[string]bool{}
+	for _, name := range columns {
+		if seen[name] {
+			errors = append(errors, errorAt("Column "+name+" is repeated in "+what, pos))
+		} else if t.Column(name) == nil {
+			errors = append(errors, errorAt("Unknown column "+name+" in "+what+" of table "+t.Name, pos))
+		}
+		seen[name] = true
+	}
+	return errors
+}
+
+// Column returns the column with the given name, or nil if the table has
+// none.
+func (t *Table) Column(name string) *Column {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
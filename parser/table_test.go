@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var tableConstraintsTests = []struct {
+	name string
+	input string
+	expected Table
+}{
+	{"Composite Primary Key", "[enrolment]\nstudent int\ncourse int\n!primary key(student, course)", Table{
+		Name: "enrolment",
+		Columns: []*Column{
+			{Name: "student", Type: "int"},
+			{Name: "course", Type: "int"},
+		},
+		Constraints: []*TableConstraint{
+			{Name: "primary key", Columns: []string{"student", "course"}},
+		},
+	}},
+	{"Several Constraints", "[users]\nemail string\ntenant int\n!unique(email, tenant) # one account each\n!unique (email)", Table{
+		Name: "users",
+		Columns: []*Column{
+			{Name: "email", Type: "string"},
+			{Name: "tenant", Type: "int"},
+		},
+		Constraints: []*TableConstraint{
+			{Name: "unique", Columns: []string{"email", "tenant"}, Comment: "one account each"},
+			{Name: "unique", Columns: []string{"email"}},
+		},
+	}},
+	{"Before Its Columns", "[pair]\n!primary key(a, b)\na int\nb int\n- NOT NULL", Table{
+		Name: "pair",
+		Columns: []*Column{
+			{Name: "a", Type: "int"},
+			{Name: "b", Type: "int", Constraints: []*Constraint{{Name: "NOT NULL"}}},
+		},
+		Constraints: []*TableConstraint{
+			{Name: "primary key", Columns: []string{"a", "b"}},
+		},
+	}},
+}
+
+func TestParseTableConstraints(t *testing.T) {
+	for _, test := range tableConstraintsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tab, errs := ParseTable(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !tab.Equals(test.expected) {
+				tt.Fatalf("Incorrect values parsed. Expected %s; got %s", &test.expected, tab)
+			}
+			again, errs := ParseTable(DummyScanner(tab.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Table not preserved by String: %s", tab)
+			}
+		})
+	}
+}
+
+var tableConstraintsRejectsTests = []struct {
+	name string
+	input string
+	message string
+	kept int
+}{
+	{"Missing Column List", "[t]\na int\n!unique", "Missing column list", 0},
+	{"Empty Column List", "[t]\na int\n!unique()", "Invalid column name", 0},
+	{"Unterminated Column List", "[t]\na int\n!unique(a", "Unterminated column list", 0},
+	{"Missing Name", "[t]\na int\n!(a)", "Ill-formed table constraint", 0},
+	{"Trailing Text", "[t]\na int\n!unique(a) deferred", "Unexpected text", 0},
+	{"Unknown Column", "[t]\na int\n!primary key(a, b)", "Unknown column b in primary key of table t", 1},
+	{"Repeated Column", "[t]\na int\n!unique(a, a)", "Column a is repeated", 1},
+}
+
+func TestParseTableConstraintsRejects(t *testing.T) {
+	for _, test := range tableConstraintsRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tab, errs := ParseTable(DummyScanner(test.input))
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.message) {
+				tt.Fatalf("Expected one %q error; got %v", test.message, errs)
+			}
+			if !strings.HasPrefix(errs[0].Error(), "3:") {
+				tt.Fatalf("Error reported at the wrong line: %v", errs[0])
+			}
+			if len(tab.Constraints) != test.kept || len(tab.Columns) != 1 {
+				tt.Fatalf("Incorrect table kept: %s", tab)
+			}
+			if len(tab.Bad) != 1-test.kept {
+				tt.Fatalf("Incorrect bad lines: %v", tab.Bad)
+			}
+		})
+	}
+}
+
+var indexMatchesTests = []struct {
+	name string
+	input string
+	expected Index
+}{
+	{"Single Column", "!index by_email(email)", Index{Name: "by_email", Columns: []string{"email"}}},
+	{"Unnamed", "!index(email, tenant)", Index{Columns: []string{"email", "tenant"}}},
+	{"Unique", "!unique index by_email(email)", Index{Name: "by_email", Columns: []string{"email"}, Unique: true}},
+	{"Upper Case", "!UNIQUE INDEX by_email(email) using HASH", Index{Name: "by_email", Columns: []string{"email"}, Unique: true, Method: "hash"}},
+	{"Method", "!index tags(tags) using gin", Index{Name: "tags", Columns: []string{"tags"}, Method: "gin"}},
+	{"Partial", "!index live(email) where deleted_at IS NULL", Index{Name: "live", Columns: []string{"email"}, Where: "deleted_at IS NULL"}},
+	{"Everything", "!unique index live(email, tenant) using btree where deleted_at IS NULL # one live account", Index{
+		Name: "live", Columns: []string{"email", "tenant"}, Unique: true, Method: "btree",
+		Where: "deleted_at IS NULL", Comment: "one live account",
+	}},
+	{"Hash In Predicate", "!index tagged(tag) where tag <> '#' # hash tags", Index{Name: "tagged", Columns: []string{"tag"}, Where: "tag <> '#'", Comment: "hash tags"}},
+	{"Escaped Hash In Predicate", `!index tagged(tag) where tag \# 1 > 0`, Index{Name: "tagged", Columns: []string{"tag"}, Where: "tag # 1 > 0"}},
+}
+
+func TestParseIndexMatches(t *testing.T) {
+	for _, test := range indexMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			idx, errs := ParseIndex(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !idx.Equals(test.expected) {
+				tt.Fatalf("Incorrect index. Expected %s; got %s", &test.expected, idx)
+			}
+			again, errs := ParseIndex(DummyScanner(idx.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Index not preserved by String: %s", idx)
+			}
+		})
+	}
+}
+
+var indexRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Missing Columns", "!index by_email", "Missing column list"},
+	{"Missing Method", "!index by_email(email) using", "Missing index method"},
+	{"Unknown Method", "!index by_email(email) using rtree", "Unknown index method rtree"},
+	{"Missing Predicate", "!index by_email(email) where", "Missing index predicate"},
+	{"Method After Predicate", "!index by_email(email) where a > 0 using gin", ""},
+	{"Trailing Text", "!index by_email(email) concurrently", "Unexpected text after index"},
+}
+
+func TestParseIndexRejects(t *testing.T) {
+	for _, test := range indexRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			idx, errs := ParseIndex(DummyScanner(test.input))
+			if test.message == "" {
+				// the predicate runs to the end of the line
+				if errs != nil || idx.Method != "" {
+					tt.Fatalf("Method should be part of the predicate: %v %v", idx, errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), test.message) {
+				tt.Fatalf("Expected one %q error; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+func TestTableIndexes(t *testing.T) {
+	input := "[users]\nemail string\ntenant int\n!unique(email, tenant)\n!unique index by_email(email)\n!index(tenant) using hash"
+	tab, errs := ParseTable(DummyScanner(input))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(tab.Constraints) != 1 || len(tab.Indexes) != 2 {
+		t.Fatalf("Incorrect table: %s", tab)
+	}
+	again, errs := ParseTable(DummyScanner(tab.String()))
+	if errs != nil || again.String() != tab.String() {
+		t.Fatalf("Table not preserved by String: %s", tab)
+	}
+
+	_, errs = ParseTable(DummyScanner("[users]\nemail string\n!index a(email)\n!index a(name)"))
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "Index a is declared more than once") ||
+		!strings.Contains(errs[1].Error(), "Unknown column name in index a of table users") {
+		t.Fatalf("Expected duplicate and unknown column errors; got %v", errs)
+	}
+}
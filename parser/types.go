@@ -0,0 +1,254 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"orb/lexer"
+)
+
+// TypeRef is the structure of a column type. A scalar type has a name and
+// possibly arguments; a collection has an element type, which can itself be
+// a collection:
+//
+//	tags []string
+//	scores list<int>
+//	labels set<string(32)>
+//	grid [][]int
+//
+// []T is another way to write list<T>.
+type TypeRef struct {
+	Name string
+	// Args holds the whole-number arguments, as in string(255).
+	Args []int
+	// Elem is the element type of a collection, and nil for other types.
+	Elem *TypeRef
+}
+
+func (t *TypeRef) String() string {
+	if t.Elem == nil {
+		return typeString(t.Name, t.Args)
+	}
+	if t.Name == "list" {
+		return "[]" + t.Elem.String()
+	}
+	return t.Name + "<" + t.Elem.String() + ">"
+}
+
+// collections are the type names that take an element type.
+var collections = map[string]bool{
+	"list": true,
+	"set": true,
+}
+
+// parseType reads a column type: a word, optionally followed directly by
+// whole-number arguments in parentheses, as in string(255) or
+// decimal(10, 2), or a collection of another type. It returns a TypeRef with
+// no name if there is no type to read.
+func parseType(c *cursor) (*TypeRef, error) {
+	first := c.peek()
+	if first.Kind == lexer.LBracket {
+		c.next()
+		if !c.joined() || !c.accept(lexer.RBracket) {
+			return nil, errorAt("Invalid column type", first.Pos)
+		}
+		return parseElem(c, &TypeRef{Name: "list"}, first)
+	}
+
+	start := c.i
+	for !c.done() && c.peek().Kind != lexer.LParen && !optionalMark(c.peek()) && !angle(c.peek(), "<") && !angle(c.peek(), ">") &&
+		(c.i == start || c.joined()) {
+		c.next()
+	}
+	t := &TypeRef{Name: c.text(start, c.i)}
+	if t.Name == "" {
+		return t, nil
+	}
+	if c.peek().Kind == lexer.LParen && c.joined() {
+		args, err := parseTypeArgs(c)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := scalarArgs[t.Name]; ok && len(args) > n {
+			return nil, errorAt(argsError(t.Name, n), first.Pos)
+		}
+		t.Args = args
+	}
+	if !angle(c.peek(), "<") || !c.joined() {
+		return t, nil
+	}
+	if !collections[t.Name] {
+		return nil, errorAt("Unknown collection type "+t.Name, first.Pos)
+	}
+	open := c.next()
+	t, err := parseElem(c, t, first)
+	if err != nil {
+		return nil, err
+	}
+	if !angle(c.peek(), ">") {
+		return nil, errorAt("Unterminated element type", open.Pos)
+	}
+	c.next()
+	return t, nil
+}
+
+// parseElem reads the element type of collection t, which starts at tok.
+func parseElem(c *cursor, t *TypeRef, tok lexer.Token) (*TypeRef, error) {
+	if c.done() || !c.joined() {
+		return nil, errorAt("Missing element type for "+t.Name, tok.Pos)
+	}
+	elem, err := parseType(c)
+	if err != nil {
+		return nil, err
+	}
+	if elem.Name == "" {
+		return nil, errorAt("Missing element type for "+t.Name, tok.Pos)
+	}
+	t.Elem = elem
+	return t, nil
+}
+
+// parseTypeArgs reads the parenthesised arguments of a type.
+func parseTypeArgs(c *cursor) ([]int, error) {
+	open := c.next()
+	var args []int
+	for {
+		tok := c.peek()
+		n, err := strconv.Atoi(tok.Text)
+		if tok.Kind != lexer.Ident || err != nil {
+			return nil, errorAt("Type arguments must be whole numbers", tok.Pos)
+		}
+		c.next()
+		args = append(args, n)
+		if !c.accept(lexer.Comma) {
+			break
+		}
+	}
+	if !c.accept(lexer.RParen) {
+		return nil, errorAt("Unterminated type arguments", open.Pos)
+	}
+	return args, nil
+}
+
+// argsError describes a built-in type given more than the n arguments it
+// takes.
+func argsError(name string, n int) string {
+	switch n {
+	case 0:
+		return "Type " + name + " takes no arguments"
+	case 1:
+		return "Type " + name + " takes at most 1 argument"
+	}
+	return "Type " + name + " takes at most " + strconv.Itoa(n) + " arguments"
+}
+
+// angle reports whether tok is the angle bracket a.
+func angle(tok lexer.Token, a string) bool {
+	return tok.Kind == lexer.Other && tok.Text == a
+}
+
+// optionalMark reports whether tok is the ? that marks a type as nullable.
+func optionalMark(tok lexer.Token) bool {
+	return tok.Kind == lexer.Other && tok.Text == "?"
+}
+
+// parseOptional reads the markers around a type that make a column
+// nullable: `optional` before it, or ? directly after it. It is called
+// before and after parseType, and reports whether it found one.
+func parseOptional(c *cursor) bool {
+	if optionalMark(c.peek()) && c.joined() {
+		c.next()
+		return true
+	}
+	if keyword(c.peek(), "optional") && !c.done() {
+		switch c.toks[c.i+1].Kind {
+		case lexer.EOF, lexer.Using:
+			// the type itself is called optional
+			return false
+		}
+		c.next()
+		return true
+	}
+	return false
+}
+
+// checkNullable reconciles Nullable with the column's NULL and NOT NULL
+// constraints. A NULL constraint makes the column nullable; a NOT NULL or
+// PRIMARY KEY constraint contradicts that.
+func (c *Column) checkNullable() []error {
+	var errors []error
+	null, notNull := nullConstraints(c.Constraints)
+	if notNull != nil && c.Nullable {
+		errors = append(errors, errorAt("Column "+c.Name+" is optional but has a "+notNull.Name+" constraint", notNull.Span.Start))
+	} else if notNull != nil && null != nil {
+		errors = append(errors, errorAt("Column "+c.Name+" has both "+null.Name+" and "+notNull.Name+" constraints", null.Span.Start))
+	}
+	if null != nil {
+		c.Nullable = true
+	}
+	return errors
+}
+
+// nullConstraints returns the last NULL constraint and the first NOT NULL or
+// PRIMARY KEY constraint among cons, or nil for either if there is none.
+// Constraints that only apply to some dialects are left to Project.
+func nullConstraints(cons []*Constraint) (null, notNull *Constraint) {
+	for _, con := range cons {
+		if con.Dialects != nil {
+			continue
+		}
+		switch nullKind(con) {
+		case "NULL":
+			null = con
+		case "NOT NULL":
+			if notNull == nil {
+				notNull = con
+			}
+		}
+	}
+	return null, notNull
+}
+
+// nullKind returns "NULL" for a NULL constraint, "NOT NULL" for a NOT NULL or
+// PRIMARY KEY constraint, and "" for any other.
+func nullKind(con *Constraint) string {
+	if con.Value != "" {
+		return ""
+	}
+	switch name := strings.ToUpper(strings.Join(strings.Fields(con.Name), " ")); name {
+	case "NULL", "NOT NULL":
+		return name
+	case "PRIMARY KEY":
+		return "NOT NULL"
+	}
+	return ""
+}
+
+// NotNullImplied reports whether the column can't hold NULL but has no NOT
+// NULL or PRIMARY KEY constraint that says so, as with a plain `id int`.
+func (c *Column) NotNullImplied() bool {
+	_, notNull := nullConstraints(c.Constraints)
+	return !c.Nullable && notNull == nil
+}
+
+// hasNull reports whether the column has a NULL constraint.
+func (c *Column) hasNull() bool {
+	for _, con := range c.Constraints {
+		if con.Dialects == nil && nullKind(con) == "NULL" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeString formats a type and its arguments as they would be written.
+func typeString(typ string, args []int) string {
+	if args == nil {
+		return typ
+	}
+	var parts []string
+	for _, arg := range args {
+		parts = append(parts, strconv.Itoa(arg))
+	}
+	return typ + "(" + strings.Join(parts, ",") + ")"
+}
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"testing"
+)
+
+var nullableConflictsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Optional And Not Null", "name string?\n- not null", "2:1:Column name is optional but has a not null constraint"},
+	{"Optional Keyword And Primary Key", "id optional int\n- Primary Key", "2:1:Column id is optional but has a Primary Key constraint"},
+	{"Null And Not Null", "name string\n-NOT NULL\n-NULL", "3:1:Column name has both NULL and NOT NULL constraints"},
+}
+
+func TestNullableConflicts(t *testing.T) {
+	for _, test := range nullableConflictsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+			if col == nil || !col.Nullable {
+				tt.Fatalf("Column should be kept as nullable: %v", col)
+			}
+		})
+	}
+}
+
+var notNullImpliedTests = []struct {
+	input string
+	nullable bool
+	implied bool
+}{
+	{"id int", false, true},
+	{"id int\n-NOT NULL", false, false},
+	{"id int\n-primary key", false, false},
+	{"id int?", true, false},
+	{"id int\n-NULL", true, false},
+}
+
+func TestNotNullImplied(t *testing.T) {
+	for _, test := range notNullImpliedTests {
+		t.Run(test.input, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if col.Nullable != test.nullable || col.NotNullImplied() != test.implied {
+				tt.Fatalf("Expected Nullable %v and NotNullImplied %v; got %v and %v", test.nullable, test.implied, col.Nullable, col.NotNullImplied())
+			}
+		})
+	}
+}
+
+var typeRefMatchesTests = []struct {
+	name string
+	input string
+	expected TypeRef
+	str string
+}{
+	{"Scalar", "a int", TypeRef{Name: "int"}, "int"},
+	{"Scalar With Arguments", "a decimal(10, 2)", TypeRef{Name: "decimal", Args: []int{10, 2}}, "decimal(10,2)"},
+	{"Slice", "tags []string", TypeRef{Name: "list", Elem: &TypeRef{Name: "string"}}, "[]string"},
+	{"List", "scores list<int>", TypeRef{Name: "list", Elem: &TypeRef{Name: "int"}}, "[]int"},
+	{"Set With Element Arguments", "labels set<string(32)>", TypeRef{Name: "set", Elem: &TypeRef{Name: "string", Args: []int{32}}}, "set<string(32)>"},
+	{"Nested", "grid [][]int", TypeRef{Name: "list", Elem: &TypeRef{Name: "list", Elem: &TypeRef{Name: "int"}}}, "[][]int"},
+	{"Nested Generic", "groups set<list<int>>", TypeRef{Name: "set", Elem: &TypeRef{Name: "list", Elem: &TypeRef{Name: "int"}}}, "set<[]int>"},
+	{"Optional Collection", "tags []string? using TEXT[]", TypeRef{Name: "list", Elem: &TypeRef{Name: "string"}}, "[]string"},
+}
+
+func typeRefEquals(t, u *TypeRef) bool {
+	if t == nil || u == nil {
+		return t == u
+	}
+	return t.Name == u.Name && typeString("", t.Args) == typeString("", u.Args) && typeRefEquals(t.Elem, u.Elem)
+}
+
+func TestParseTypeRefMatches(t *testing.T) {
+	for _, test := range typeRefMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !typeRefEquals(col.TypeRef, &test.expected) || col.TypeRef.String() != test.str {
+				tt.Fatalf("Incorrect type. Expected %s; got %s", test.str, col.TypeRef)
+			}
+			if col.Type != test.expected.Name {
+				tt.Fatalf("Type doesn't match TypeRef: %s", col.Type)
+			}
+			again, errs := ParseColumn(DummyScanner(col.String()))
+			if errs != nil || !typeRefEquals(again.TypeRef, &test.expected) || again.Nullable != col.Nullable {
+				tt.Fatalf("Type not preserved by String: %s", col)
+			}
+		})
+	}
+}
+
+var typeRefRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Missing Element", "tags []", "1:6:Missing element type for list"},
+	{"Spaced Element", "tags [] string", "1:6:Missing element type for list"},
+	{"Unclosed Slice", "tags [string", "1:6:Invalid column type"},
+	{"Unknown Collection", "tags bag<string>", "1:6:Unknown collection type bag"},
+	{"Unterminated Element", "tags list<string", "1:10:Unterminated element type"},
+	{"Empty Element", "tags list<>", "1:6:Missing element type for list"},
+	{"Extra Bracket", "tags list<string>>", "1:6:Invalid column type"},
+	{"Int With Arguments", "id int(11)", "1:4:Type int takes no arguments"},
+	{"Bool With Arguments", "b bool(1)", "1:3:Type bool takes no arguments"},
+	{"Float With Arguments", "x float(53)", "1:3:Type float takes no arguments"},
+	{"String With Two Arguments", "s string(10, 2)", "1:3:Type string takes at most 1 argument"},
+	{"Decimal With Three Arguments", "d decimal(10, 2, 1)", "1:3:Type decimal takes at most 2 arguments"},
+	{"Element With Arguments", "ids []int(4)", "1:7:Type int takes no arguments"},
+}
+
+func TestParseTypeRefRejects(t *testing.T) {
+	for _, test := range typeRefRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := ParseColumn(DummyScanner(test.input))
+			if len(errs) != 1 || errs[0].Error() != test.message {
+				tt.Fatalf("Expected error %q; got %v", test.message, errs)
+			}
+		})
+	}
+}
+
+var typeSQLTests = []struct {
+	input string
+	postgres, mysql, sqlite string
+}{
+	{"a int", "INTEGER", "INT", "INTEGER"},
+	{"a string(255)", "VARCHAR(255)", "VARCHAR(255)", "VARCHAR(255)"},
+	{"a decimal(10,2)", "NUMERIC(10,2)", "DECIMAL(10,2)", "NUMERIC(10,2)"},
+	{"a decimal(10)", "NUMERIC(10)", "DECIMAL(10)", "NUMERIC(10)"},
+	{"a time", "TIMESTAMP", "DATETIME", "TEXT"},
+	{"a time(3)", "TIMESTAMP(3)", "DATETIME(3)", "TEXT"},
+	{"a status", "status", "status", "status"},
+	{"a []string", "TEXT[]", "JSON", "TEXT"},
+	{"a [][]int", "INTEGER[][]", "JSON", "TEXT"},
+	{"a set<string(32)>", "VARCHAR(32)[]", "JSON", "TEXT"},
+	{"a []string using JSONB", "JSONB", "JSONB", "JSONB"},
+}
+
+func TestTypeSQL(t *testing.T) {
+	for _, test := range typeSQLTests {
+		t.Run(test.input, func(tt *testing.T) {
+			col, errs := ParseColumn(DummyScanner(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			for d, expected := range map[Dialect]string{Postgres: test.postgres, MySQL: test.mysql, SQLite: test.sqlite} {
+				if got := col.SQLType(d); got != expected {
+					tt.Errorf("Incorrect %s type. Expected %s; got %s", d, expected, got)
+				}
+			}
+		})
+	}
+}
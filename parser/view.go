@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"strings"
+
+	"orb/lexer"
+)
+
+// View is a named query, declared in its own block. It lists its output
+// columns with the same syntax as a table, and gives its query on a `!sql:`
+// line, which can carry on over `|` lines:
+//
+//	[view active_students]
+//	sid int
+//	name string
+//	!sql: SELECT sid, name FROM student
+//	  | WHERE active
+type View struct {
+	// Namespace and Name are as for Table, and #schema applies to views too.
+	Namespace string
+	Name string
+	// Doc is the text of the comment lines directly above the header.
+	Doc string
+	// Comment is the trailing comment on the header line.
+	Comment string
+	Columns []*Column
+	// SQL is the query, with its lines joined by newlines.
+	SQL string
+	// SQLSpan covers the !sql line and its continuation lines.
+	SQLSpan Span
+	Span Span
+	// Bad holds lines that couldn't be parsed.
+	Bad []*BadLine
+	// defaultNamespace is set if Namespace came from #schema.
+	defaultNamespace bool
+}
+
+func (v *View) String() string {
+	name := v.QualifiedName()
+	if v.defaultNamespace {
+		name = v.Name
+	}
+	s := docString(v.Doc) + "[view " + name + "]" + commentString(v.Comment)
+	for _, c := range v.Columns {
+		s += "\n" + c.String()
+	}
+	if v.SQL != "" {
+		s += "\n!sql:"
+		for i, line := range strings.Split(v.SQL, "\n") {
+			if i > 0 {
+				s += "\n  |"
+			}
+			if line != "" {
+				s += " " + escapeComments(line)
+			}
+		}
+	}
+	return s + "\n"
+}
+
+// QualifiedName returns the view's name with its namespace, if it has one.
+func (v *View) QualifiedName() string {
+	if v.Namespace == "" {
+		return v.Name
+	}
+	return v.Namespace + "." + v.Name
+}
+
+// CreateSQL returns the statement that creates the view.
+func (v *View) CreateSQL() string {
+	var columns []string
+	for _, c := range v.Columns {
+		columns = append(columns, c.Name)
+	}
+	return "CREATE VIEW " + v.QualifiedName() + " (" + strings.Join(columns, ", ") + ") AS\n" + v.SQL + ";"
+}
+
+// View returns the view with the given name, or nil if there is none. Names
+// are matched as for Table.
+func (p *ParseTree) View(name string) *View {
+	for _, v := range p.Views {
+		if v.QualifiedName() == name || !strings.Contains(name, ".") && v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// isViewHeader reports whether a line starting with [ opens a view rather
+// than a table.
+func isViewHeader(c *cursor) bool {
+	return len(c.toks) > 3 && keyword(c.toks[1], "view") && c.toks[2].Kind == lexer.Ident
+}
+
+// ParseView reads a view header, its columns and its query, up to a blank
+// line or the next header. Lines that fail to parse are recorded in
+// View.Bad. If the header itself is invalid, the whole block is skipped and
+// no view is returned.
+func ParseView(input Input) (*View, []error) {
+	input.Scan()
+	c := newCursor(input)
+	view := &View{Comment: c.comment, Span: c.lineSpan()}
+	ok := c.accept(lexer.LBracket) && keyword(c.peek(), "view")
+	if ok {
+		c.next()
+		view.Namespace, view.Name, ok = splitTableName(c.word(lexer.Ident, lexer.Dash, lexer.Dot))
+	}
+	if !ok || view.Name == "" || !c.accept(lexer.RBracket) || !c.done() {
+		err := NewError("Invalid view name", input)
+		skipBlock(input)
+		return nil, []error{err}
+	}
+
+	var errors []error
+	var doc []string
+	for input.Scan() {
+		c := newCursor(input)
+		switch c.peek().Kind {
+		case lexer.EOF:
+			return view, append(errors, view.check()...)
+		case lexer.Hash:
+			doc = append(doc, commentText(input.Text()))
+			continue
+		case lexer.LBracket:
+			input.Backtrack()
+			return view, append(errors, view.check()...)
+		}
+		bad := newBadLine(input, nil)
+		input.Backtrack()
+		if c.peek().Kind == lexer.Bang {
+			if errs := parseViewSQL(input, view); errs != nil {
+				errors = append(errors, errs...)
+				bad.Err = errs[0]
+				view.Bad = append(view.Bad, bad)
+			}
+			doc = nil
+			continue
+		}
+		col, errs := ParseColumn(input)
+		errors = append(errors, errs...)
+		if col != nil {
+			col.Doc = strings.Join(doc, "\n")
+			view.Columns = append(view.Columns, col)
+			view.Span.End = col.Span.End
+		} else {
+			bad.Err = errs[0]
+			view.Bad = append(view.Bad, bad)
+		}
+		doc = nil
+	}
+	return view, append(errors, view.check()...)
+}
+
+// parseViewSQL reads a `!sql: query` line and its continuation lines into
+// view.
+func parseViewSQL(input Input, view *View) []error {
+	input.Scan()
+	c := newCursor(input)
+	if c.illegal() {
+		return []error{NewError("Unterminated string literal", input)}
+	}
+	if !c.accept(lexer.Bang) || !keyword(c.peek(), "sql") {
+		return []error{NewError("Views only have columns and a !sql line", input)}
+	}
+	c.next()
+	if !c.accept(lexer.Colon) {
+		return []error{errorAt("Missing : after sql", c.peek().Pos)}
+	}
+	if view.SQL != "" {
+		return []error{NewError("View "+view.Name+" already has its SQL at "+view.SQLSpan.Start.String(), input)}
+	}
+	span := c.lineSpan()
+	lines := []string{c.rest()}
+	for input.Scan() {
+		c := newCursor(input)
+		if !c.accept(lexer.Pipe) {
+			input.Backtrack()
+			break
+		}
+		if c.illegal() {
+			return []error{NewError("Unterminated string literal", input)}
+		}
+		lines = append(lines, c.restIndented())
+		span.End = c.lineSpan().End
+	}
+	view.SQL = strings.TrimSpace(strings.Join(lines, "\n"))
+	if view.SQL == "" {
+		return []error{errorAt("Missing SQL for view "+view.Name, span.End)}
+	}
+	view.SQLSpan = span
+	view.Span.End = span.End
+	return nil
+}
+
+// check reports a view with no columns or no query. A view with bad lines
+// has already been reported, and may only be missing what failed to parse.
+func (v *View) check() []error {
+	if v.Bad != nil {
+		return nil
+	}
+	var errors []error
+	if v.Columns == nil {
+		errors = append(errors, errorAt("View "+v.Name+" has no columns", v.Span.Start))
+	}
+	if v.SQL == "" {
+		errors = append(errors, errorAt("View "+v.Name+" has no SQL", v.Span.Start))
+	}
+	return errors
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var viewMatchesTests = []struct {
+	name string
+	input string
+	expected ParseTree
+}{
+	{"Single Line", "[view names]\nname string\n!sql: SELECT name FROM student", ParseTree{Views: []*View{
+		{Name: "names", Columns: []*Column{{Name: "name", Type: "string"}}, SQL: "SELECT name FROM student"},
+	}}},
+	{"Continued", "[view active]\nsid int\nname string\n!sql: SELECT sid, name\n  | FROM student\n  |   WHERE active", ParseTree{Views: []*View{
+		{Name: "active", Columns: []*Column{{Name: "sid", Type: "int"}, {Name: "name", Type: "string"}}, SQL: "SELECT sid, name\nFROM student\n  WHERE active"},
+	}}},
+	{"Docs And Comments", "# Current students\n[view active] # for reports\n# their id\nsid int # unique\n!sql: SELECT sid FROM student", ParseTree{Views: []*View{
+		{Name: "active", Doc: "Current students", Comment: "for reports", Columns: []*Column{{Name: "sid", Type: "int", Doc: "their id", Comment: "unique"}}, SQL: "SELECT sid FROM student"},
+	}}},
+	{"Namespaced", "[view school.active]\nsid int\n!sql: SELECT sid FROM school.student", ParseTree{Views: []*View{
+		{Namespace: "school", Name: "active", Columns: []*Column{{Name: "sid", Type: "int"}}, SQL: "SELECT sid FROM school.student"},
+	}}},
+	{"Alongside Tables", "[student]\nsid int\n[view ids]\nsid int\n!sql: SELECT sid FROM student\n[view]\nid int", ParseTree{
+		Tables: []*Table{
+			{Name: "student", Columns: []*Column{{Name: "sid", Type: "int"}}},
+			{Name: "view", Columns: []*Column{{Name: "id", Type: "int"}}},
+		},
+		Views: []*View{
+			{Name: "ids", Columns: []*Column{{Name: "sid", Type: "int"}}, SQL: "SELECT sid FROM student"},
+		},
+	}},
+	{"Named Column Type", "#type email = string(320)\n[view emails]\naddress email\n!sql: SELECT address FROM users", ParseTree{Views: []*View{
+		{Name: "emails", Columns: []*Column{{Name: "address", Type: "string", TypeArgs: []int{320}, TypeName: "email"}}, SQL: "SELECT address FROM users"},
+	}}},
+}
+
+func TestParseViewMatches(t *testing.T) {
+	for _, test := range viewMatchesTests {
+		t.Run(test.name, func(tt *testing.T) {
+			tree, errs := Parse(strings.NewReader(test.input))
+			if errs != nil {
+				tt.Fatalf("Unexpected errors: %v", errs)
+			}
+			if !tree.Equals(test.expected) {
+				tt.Fatalf("Incorrect tree. Expected:\n%s\ngot:\n%s", &test.expected, tree)
+			}
+			again, errs := Parse(strings.NewReader(tree.String()))
+			if errs != nil || !again.Equals(test.expected) {
+				tt.Fatalf("Tree not preserved by String:\n%s", tree)
+			}
+		})
+	}
+}
+
+var viewRejectsTests = []struct {
+	name string
+	input string
+	message string
+}{
+	{"Invalid Name", "[view two words]\nid int", "1:1:Invalid view name"},
+	{"No Columns", "[view ids]\n!sql: SELECT 1", "1:1:View ids has no columns"},
+	{"No SQL", "[view ids]\nid int", "1:1:View ids has no SQL"},
+	{"Empty SQL", "[view ids]\nid int\n!sql:", "3:6:Missing SQL for view ids"},
+	{"Missing Colon", "[view ids]\nid int\n!sql SELECT 1", "3:6:Missing : after sql"},
+	{"Table Constraint", "[view ids]\nid int\n!pk(id)", "3:1:Views only have columns and a !sql line"},
+	{"Repeated SQL", "[view ids]\nid int\n!sql: SELECT 1\n!sql: SELECT 2", "4:1:View ids already has its SQL at 3:1"},
+	{"Repeated View", "[view ids]\nid int\n!sql: SELECT 1\n\n[view ids]\nid int\n!sql: SELECT 2", "5:1:View ids is already declared at 1:1"},
+	{"Unknown Reference", "[view ids]\nid int\n-references: nope.x\n!sql: SELECT 1", "3:1:Reference to unknown table nope"},
+	{"Same As Table", "[ids]\nid int\n\n[view ids]\nid int\n!sql: SELECT 1", "4:1:View ids has the same name as the table at 1:1"},
+}
+
+func TestParseViewRejects(t *testing.T) {
+	for _, test := range viewRejectsTests {
+		t.Run(test.name, func(tt *testing.T) {
+			_, errs := Parse(strings.NewReader(test.input))
+			if len(errs) != 1 {
+				tt.Fatalf("Expected one error, got %v", errs)
+			}
+			if errs[0].Error() != test.message {
+				tt.Fatalf("Expected %q, got %q", test.message, errs[0].Error())
+			}
+		})
+	}
+}
+
+func TestViewCreateSQL(t *testing.T) {
+	tree, errs := Parse(strings.NewReader("#schema = school\n[view active]\nsid int\nname string\n!sql: SELECT sid, name\n  | FROM student"))
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	expected := "CREATE VIEW school.active (sid, name) AS\nSELECT sid, name\nFROM student;"
+	if got := tree.View("active").CreateSQL(); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+}